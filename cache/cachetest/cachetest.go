@@ -0,0 +1,215 @@
+//Package cachetest 检查 cache.Cache 的实现与 Memory、Redis、Memcache 的行为是否一致, 用于测试自定义的缓存(etcd、DynamoDB、SQL 等)
+//
+//	func TestMyCache(t *testing.T) {
+//		cachetest.Run(t, func(t *testing.T) cache.Cache {
+//			return NewMyCache(...)
+//		})
+//	}
+package cachetest
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
+)
+
+//ExpireTimeout 测试过期时使用的 timeout, Redis、Memcache 的过期时间精确到秒
+var ExpireTimeout = time.Second
+
+//ExpireWait 设置之后最多等待多久过期, memcached 的时钟每秒更新一次, 实际的过期时间可能比 timeout 晚一秒
+var ExpireWait = 5 * time.Second
+
+//Factory 创建用于测试的 Cache, 每个测试用例调用一次, 不同的测试用例使用不同的 key
+type Factory func(t *testing.T) cache.Cache
+
+type testCase struct {
+	name string
+	fn   func(t *testing.T, c cache.Cache)
+}
+
+var testCases = []testCase{
+	{"SetGet", testSetGet},
+	{"Overwrite", testOverwrite},
+	{"Missing", testMissing},
+	{"Delete", testDelete},
+	{"ValueTypes", testValueTypes},
+	{"Expire", testExpire},
+	{"Concurrent", testConcurrent},
+}
+
+//Run 运行所有测试用例, 其中过期相关的测试需要等待几秒钟
+//
+//检查的行为:
+//   - Get、GetInto 返回 Set 保存的值, 字符串通过 Get 获取时仍然是 string, 其它类型通过 cache.GetInto 获取
+//   - key 不存在时 Get 返回 nil、IsExist 返回 false、GetInto 返回 cache.ErrCacheMiss, Delete 不返回 error
+//   - 超过 timeout 之后 key 不再存在; timeout 小于等于 0 时永不过期
+//   - 可以在多个 goroutine 中并发使用
+func Run(t *testing.T, factory Factory) {
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, factory(t))
+		})
+	}
+}
+
+func testSetGet(t *testing.T, c cache.Cache) {
+	if err := c.Set("cachetest_set_get", "antsbean", time.Minute); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if !c.IsExist("cachetest_set_get") {
+		t.Error("IsExist got false after Set")
+	}
+	if val, ok := c.Get("cachetest_set_get").(string); !ok || val != "antsbean" {
+		t.Errorf("Get got %#v, want %q", c.Get("cachetest_set_get"), "antsbean")
+	}
+	var val string
+	if err := cache.GetInto(c, "cachetest_set_get", &val); err != nil || val != "antsbean" {
+		t.Errorf("GetInto got %q, err=%v", val, err)
+	}
+}
+
+func testOverwrite(t *testing.T, c cache.Cache) {
+	c.Set("cachetest_overwrite", "old", time.Minute)
+	if err := c.Set("cachetest_overwrite", "new", time.Minute); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if val, _ := cache.GetString(c, "cachetest_overwrite"); val != "new" {
+		t.Errorf("Get after overwrite got %q, want %q", val, "new")
+	}
+}
+
+func testMissing(t *testing.T, c cache.Cache) {
+	if val := c.Get("cachetest_missing"); val != nil {
+		t.Errorf("Get missing key got %#v, want nil", val)
+	}
+	if c.IsExist("cachetest_missing") {
+		t.Error("IsExist missing key got true")
+	}
+	var val string
+	if err := cache.GetInto(c, "cachetest_missing", &val); err != cache.ErrCacheMiss {
+		t.Errorf("GetInto missing key got err=%v, want cache.ErrCacheMiss", err)
+	}
+	if err := c.Delete("cachetest_missing"); err != nil {
+		t.Errorf("Delete missing key error: %v", err)
+	}
+}
+
+func testDelete(t *testing.T, c cache.Cache) {
+	c.Set("cachetest_delete", "antsbean", time.Minute)
+	if err := c.Delete("cachetest_delete"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if c.IsExist("cachetest_delete") {
+		t.Error("IsExist got true after Delete")
+	}
+	if val := c.Get("cachetest_delete"); val != nil {
+		t.Errorf("Get after Delete got %#v, want nil", val)
+	}
+}
+
+type valueStruct struct {
+	Name   string            `json:"name"`
+	Count  int64             `json:"count"`
+	Tags   []string          `json:"tags"`
+	Extra  map[string]string `json:"extra"`
+	Nested *valueStruct      `json:"nested,omitempty"`
+}
+
+func testValueTypes(t *testing.T, c cache.Cache) {
+	values := []interface{}{
+		"",
+		"中文 with spaces\r\n",
+		int64(1234567890123),
+		-1.5,
+		true,
+		[]string{"a", "b"},
+		map[string]int{"a": 1},
+		valueStruct{
+			Name:   "antsbean",
+			Count:  42,
+			Tags:   []string{"wechat"},
+			Extra:  map[string]string{"k": "v"},
+			Nested: &valueStruct{Name: "nested"},
+		},
+		&valueStruct{Name: "pointer"},
+	}
+	for i, want := range values {
+		key := fmt.Sprintf("cachetest_value_%d", i)
+		if err := c.Set(key, want, time.Minute); err != nil {
+			t.Errorf("Set %T error: %v", want, err)
+			continue
+		}
+		got := reflect.New(reflect.TypeOf(want))
+		if err := cache.GetInto(c, key, got.Interface()); err != nil {
+			t.Errorf("GetInto %T error: %v", want, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Elem().Interface(), want) {
+			t.Errorf("GetInto %T got %#v, want %#v", want, got.Elem().Interface(), want)
+		}
+	}
+}
+
+func testExpire(t *testing.T, c cache.Cache) {
+	timeouts := map[string]time.Duration{
+		"cachetest_expire_short":    ExpireTimeout,
+		"cachetest_expire_long":     time.Hour,
+		"cachetest_expire_zero":     0,
+		"cachetest_expire_negative": -time.Second,
+	}
+	for key, timeout := range timeouts {
+		defer c.Delete(key)
+		if err := c.Set(key, "antsbean", timeout); err != nil {
+			t.Fatalf("Set with timeout %v error: %v", timeout, err)
+		}
+		if !c.IsExist(key) {
+			t.Errorf("IsExist got false right after Set with timeout %v", timeout)
+		}
+	}
+
+	deadline := time.Now().Add(ExpireWait)
+	for c.IsExist("cachetest_expire_short") && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if c.IsExist("cachetest_expire_short") || c.Get("cachetest_expire_short") != nil {
+		t.Errorf("key with timeout %v still exists after %v", ExpireTimeout, ExpireWait)
+	}
+	for _, key := range []string{"cachetest_expire_long", "cachetest_expire_zero", "cachetest_expire_negative"} {
+		if val, _ := cache.GetString(c, key); val != "antsbean" {
+			t.Errorf("key with timeout %v expired too early", timeouts[key])
+		}
+	}
+}
+
+func testConcurrent(t *testing.T, c cache.Cache) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("cachetest_concurrent_%d", i)
+			for j := 0; j < 50; j++ {
+				want := fmt.Sprintf("%d_%d", i, j)
+				if err := c.Set(key, want, time.Minute); err != nil {
+					t.Errorf("Set error: %v", err)
+					return
+				}
+				if val, _ := cache.GetString(c, key); val != want {
+					t.Errorf("Get %s got %q, want %q", key, val, want)
+					return
+				}
+				//多个 goroutine 同时修改同一个 key
+				c.Set("cachetest_concurrent_shared", want, time.Minute)
+				c.Get("cachetest_concurrent_shared")
+				c.IsExist("cachetest_concurrent_shared")
+			}
+			c.Delete(key)
+		}(i)
+	}
+	wg.Wait()
+}
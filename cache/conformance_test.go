@@ -0,0 +1,27 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/cache/cachetest"
+)
+
+//closers 保存 cachetest.Factory 创建的 Cache, 在测试结束时关闭
+type closers []func() error
+
+func (c *closers) close() {
+	for _, fn := range *c {
+		fn()
+	}
+}
+
+func TestMemoryConformance(t *testing.T) {
+	var caches closers
+	defer caches.close()
+	cachetest.Run(t, func(t *testing.T) cache.Cache {
+		mem := cache.NewMemory()
+		caches = append(caches, mem.Close)
+		return mem
+	})
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"time"
+
+	"github.com/antsbean/wechat/util"
+
+	goredis "github.com/go-redis/redis/v7"
+)
+
+//GoRedis 基于 go-redis 的缓存, 支持单节点、Cluster、Sentinel 以及 TLS、ACL 用户
+type GoRedis struct {
+	client goredis.UniversalClient
+	prefix string
+}
+
+//GoRedisOpts go-redis 连接属性
+type GoRedisOpts struct {
+	//Addrs 单节点时为一个地址; 多个地址时使用 Cluster; 设置了 MasterName 时为 Sentinel 的地址
+	Addrs []string `yml:"addrs" json:"addrs"`
+	//Cluster 只有一个地址时也使用 Cluster
+	Cluster bool `yml:"cluster" json:"cluster"`
+	//MasterName Sentinel 监控的 master 名称, 设置后通过 Sentinel 自动切换 master
+	MasterName       string `yml:"master_name" json:"master_name"`
+	SentinelPassword string `yml:"sentinel_password" json:"sentinel_password"`
+
+	Username string `yml:"username" json:"username"` //Redis 6 ACL 用户, 为空时只使用密码
+	Password string `yml:"password" json:"password"`
+	Database int    `yml:"database" json:"database"` //Cluster 只能使用 0
+	//KeyPrefix 所有 key 的前缀, 多个环境共享同一个 Redis 时用于隔离
+	KeyPrefix string `yml:"key_prefix" json:"key_prefix"`
+
+	//TLS 为 true 时使用 TLS 连接, 需要自定义证书时设置 TLSConfig
+	TLS       bool        `yml:"tls" json:"tls"`
+	TLSConfig *tls.Config `yml:"-" json:"-"`
+
+	PoolSize     int   `yml:"pool_size" json:"pool_size"`
+	MinIdleConns int   `yml:"min_idle_conns" json:"min_idle_conns"`
+	DialTimeout  int32 `yml:"dial_timeout" json:"dial_timeout"`   //millisecond
+	ReadTimeout  int32 `yml:"read_timeout" json:"read_timeout"`   //millisecond
+	WriteTimeout int32 `yml:"write_timeout" json:"write_timeout"` //millisecond
+}
+
+//NewGoRedis 实例化
+func NewGoRedis(opts *GoRedisOpts) *GoRedis {
+	tlsConfig := opts.TLSConfig
+	if tlsConfig == nil && opts.TLS {
+		tlsConfig = &tls.Config{}
+	}
+	dialTimeout := time.Duration(opts.DialTimeout) * time.Millisecond
+	readTimeout := time.Duration(opts.ReadTimeout) * time.Millisecond
+	writeTimeout := time.Duration(opts.WriteTimeout) * time.Millisecond
+
+	var client goredis.UniversalClient
+	switch {
+	case opts.MasterName != "":
+		client = goredis.NewFailoverClient(&goredis.FailoverOptions{
+			MasterName:       opts.MasterName,
+			SentinelAddrs:    opts.Addrs,
+			SentinelPassword: opts.SentinelPassword,
+			Username:         opts.Username,
+			Password:         opts.Password,
+			DB:               opts.Database,
+			TLSConfig:        tlsConfig,
+			PoolSize:         opts.PoolSize,
+			MinIdleConns:     opts.MinIdleConns,
+			DialTimeout:      dialTimeout,
+			ReadTimeout:      readTimeout,
+			WriteTimeout:     writeTimeout,
+		})
+	case opts.Cluster || len(opts.Addrs) > 1:
+		client = goredis.NewClusterClient(&goredis.ClusterOptions{
+			Addrs:        opts.Addrs,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			TLSConfig:    tlsConfig,
+			PoolSize:     opts.PoolSize,
+			MinIdleConns: opts.MinIdleConns,
+			DialTimeout:  dialTimeout,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+		})
+	default:
+		var addr string
+		if len(opts.Addrs) > 0 {
+			addr = opts.Addrs[0]
+		}
+		client = goredis.NewClient(&goredis.Options{
+			Addr:         addr,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			DB:           opts.Database,
+			TLSConfig:    tlsConfig,
+			PoolSize:     opts.PoolSize,
+			MinIdleConns: opts.MinIdleConns,
+			DialTimeout:  dialTimeout,
+			ReadTimeout:  readTimeout,
+			WriteTimeout: writeTimeout,
+		})
+	}
+	return NewGoRedisWithClient(client, opts.KeyPrefix)
+}
+
+//NewGoRedisWithClient 使用已经创建的 go-redis client 实例化, 所有 key 都加上 prefix
+func NewGoRedisWithClient(client goredis.UniversalClient, prefix string) *GoRedis {
+	return &GoRedis{client: client, prefix: prefix}
+}
+
+//Client 返回 go-redis client
+func (r *GoRedis) Client() goredis.UniversalClient {
+	return r.client
+}
+
+//Close 关闭连接
+func (r *GoRedis) Close() error {
+	return r.client.Close()
+}
+
+func (r *GoRedis) key(key string) string {
+	return r.prefix + key
+}
+
+//Get 获取一个值
+func (r *GoRedis) Get(key string) interface{} {
+	var reply interface{}
+	if err := r.GetInto(key, &reply); err != nil {
+		return nil
+	}
+	return reply
+}
+
+//GetInto 获取一个值并解析到 val 指向的变量中, 实现 TypedGetter 接口
+func (r *GoRedis) GetInto(key string, val interface{}) error {
+	data, err := r.client.Get(r.key(key)).Bytes()
+	if err == goredis.Nil {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	return unmarshal(data, val)
+}
+
+//Set 设置一个值, timeout 小于等于 0 时永不过期
+func (r *GoRedis) Set(key string, val interface{}, timeout time.Duration) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	if timeout < 0 {
+		timeout = 0
+	}
+	return r.client.Set(r.key(key), data, timeout).Err()
+}
+
+//TTL 返回 key 的剩余有效期, 实现 TTLGetter 接口
+func (r *GoRedis) TTL(key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(r.key(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	//key 不存在或者永不过期时 go-redis 直接返回 -2、-1
+	if ttl < 0 {
+		return pttl(int64(ttl))
+	}
+	return ttl, nil
+}
+
+//IsExist 判断key是否存在
+func (r *GoRedis) IsExist(key string) bool {
+	n, err := r.client.Exists(r.key(key)).Result()
+	return err == nil && n > 0
+}
+
+//Delete 删除
+func (r *GoRedis) Delete(key string) error {
+	return r.client.Del(r.key(key)).Err()
+}
+
+//goRedisUnlockScript 只有锁的持有者才能删除锁
+var goRedisUnlockScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+//TryLock 通过 SET NX PX 获取锁，实现 Locker 接口
+func (r *GoRedis) TryLock(key string, ttl time.Duration) (unlock func() error, ok bool, err error) {
+	key = r.key(key)
+	token := util.RandomStr(32)
+	if ok, err = r.client.SetNX(key, token, ttl).Result(); err != nil || !ok {
+		return nil, false, err
+	}
+
+	unlock = func() error {
+		return goRedisUnlockScript.Run(r.client, []string{key}, token).Err()
+	}
+	return unlock, true, nil
+}
+
+//Invalidator 通过 Redis 的 pub/sub 在多个进程之间广播需要删除本地缓存的 key, 用于 Tiered
+//channel 同样会加上 KeyPrefix, 连接断开之后 go-redis 会自动重新订阅
+func (r *GoRedis) Invalidator(channel string) Invalidator {
+	return &goRedisInvalidator{r: r, channel: r.key(channel)}
+}
+
+type goRedisInvalidator struct {
+	r       *GoRedis
+	channel string
+}
+
+func (i *goRedisInvalidator) Publish(key string) error {
+	return i.r.client.Publish(i.channel, key).Err()
+}
+
+func (i *goRedisInvalidator) Subscribe(fn func(key string)) (unsubscribe func() error, err error) {
+	pubsub := i.r.client.Subscribe(i.channel)
+	//等待订阅成功, 之后发布的消息都可以收到
+	if _, err = pubsub.Receive(); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	ch := pubsub.Channel()
+	go func() {
+		for msg := range ch {
+			fn(msg.Payload)
+		}
+	}()
+	return pubsub.Close, nil
+}
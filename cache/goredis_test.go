@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestGoRedis(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.RequireUserAuth("wechat", "secret")
+
+	r := NewGoRedis(&GoRedisOpts{
+		Addrs:     []string{s.Addr()},
+		Username:  "wechat",
+		Password:  "secret",
+		KeyPrefix: "test:",
+	})
+	defer r.Close()
+
+	if err = r.Set("username", "antsbean", 10*time.Second); err != nil {
+		t.Fatal("set Error", err)
+	}
+	if !r.IsExist("username") {
+		t.Error("IsExist Error")
+	}
+	if name, _ := r.Get("username").(string); name != "antsbean" {
+		t.Error("get Error")
+	}
+	if !s.Exists("test:username") {
+		t.Error("expect key with prefix")
+	}
+	if ttl := s.TTL("test:username"); ttl != 10*time.Second {
+		t.Errorf("expect ttl 10s, got %v", ttl)
+	}
+
+	r.Set("struct", typedValue{Name: "antsbean", Count: 42}, time.Minute)
+	var v typedValue
+	if err = GetInto(r, "struct", &v); err != nil || v.Count != 42 {
+		t.Errorf("GetInto got %+v, err=%v", v, err)
+	}
+	if err = GetInto(r, "missing", &v); err != ErrCacheMiss {
+		t.Errorf("expect ErrCacheMiss, got %v", err)
+	}
+
+	if err = r.Delete("username"); err != nil {
+		t.Errorf("delete Error , err=%v", err)
+	}
+	if r.IsExist("username") || r.Get("username") != nil {
+		t.Error("expect deleted")
+	}
+}
+
+func TestGoRedisTryLock(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := NewGoRedis(&GoRedisOpts{Addrs: []string{s.Addr()}, KeyPrefix: "test:"})
+	defer r.Close()
+
+	unlock, ok, err := r.TryLock("lock", 10*time.Second)
+	if !ok || err != nil {
+		t.Fatalf("TryLock Error , ok=%v , err=%v", ok, err)
+	}
+	if _, ok, _ = r.TryLock("lock", 10*time.Second); ok {
+		t.Error("expect lock held by others")
+	}
+	if err = unlock(); err != nil {
+		t.Errorf("unlock Error , err=%v", err)
+	}
+	if s.Exists("test:lock") {
+		t.Error("expect lock released")
+	}
+}
+
+func TestGoRedisCluster(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	r := NewGoRedis(&GoRedisOpts{Addrs: []string{s.Addr()}, Cluster: true})
+	defer r.Close()
+
+	if err := r.Set("username", "antsbean", time.Minute); err != nil {
+		t.Fatal("set Error", err)
+	}
+	if name, _ := r.Get("username").(string); name != "antsbean" {
+		t.Error("get Error")
+	}
+}
+
+func TestGoRedisTLS(t *testing.T) {
+	//使用 httptest 自带的证书, 证书中的域名为 example.com
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	ts.Close()
+
+	s, err := miniredis.RunTLS(&tls.Config{Certificates: ts.TLS.Certificates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	r := NewGoRedis(&GoRedisOpts{
+		Addrs:     []string{s.Addr()},
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "example.com"},
+	})
+	defer r.Close()
+
+	if err = r.Set("username", "antsbean", time.Minute); err != nil {
+		t.Fatal("set Error", err)
+	}
+	if name, _ := r.Get("username").(string); name != "antsbean" {
+		t.Error("get Error")
+	}
+}
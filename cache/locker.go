@@ -0,0 +1,10 @@
+package cache
+
+import "time"
+
+//Locker 分布式锁，多个进程共享同一个缓存时，用于保证只有一个进程去刷新 access_token 等凭证
+type Locker interface {
+	//TryLock 尝试获取 key 对应的锁，锁在 ttl 之后自动释放
+	//获取成功时 ok 为 true，unlock 用于主动释放锁；锁被其它进程持有时 ok 为 false
+	TryLock(key string, ttl time.Duration) (unlock func() error, ok bool, err error)
+}
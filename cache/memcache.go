@@ -32,6 +32,18 @@ func (mem *Memcache) Get(key string) interface{} {
 	return result
 }
 
+//GetInto 获取 key 对应的值并解析到 val 指向的变量中, 实现 TypedGetter 接口
+func (mem *Memcache) GetInto(key string, val interface{}) error {
+	item, err := mem.conn.Get(key)
+	if err == memcache.ErrCacheMiss {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	return unmarshal(item.Value, val)
+}
+
 // IsExist check value exists in memcache.
 func (mem *Memcache) IsExist(key string) bool {
 	if _, err := mem.conn.Get(key); err != nil {
@@ -40,18 +52,35 @@ func (mem *Memcache) IsExist(key string) bool {
 	return true
 }
 
-//Set cached value with key and expire time.
+//Set cached value with key and expire time, timeout 小于等于 0 时永不过期
 func (mem *Memcache) Set(key string, val interface{}, timeout time.Duration) (err error) {
 	var data []byte
 	if data, err = json.Marshal(val); err != nil {
 		return err
 	}
 
-	item := &memcache.Item{Key: key, Value: data, Expiration: int32(timeout / time.Second)}
+	item := &memcache.Item{Key: key, Value: data, Expiration: memcacheExpiration(timeout)}
 	return mem.conn.Set(item)
 }
 
+//maxRelativeExpiration 超过 30 天的过期时间 memcached 当做 unix 时间戳处理
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+//memcacheExpiration 转换为 memcached 的过期时间, 不足一秒按一秒处理
+func memcacheExpiration(timeout time.Duration) int32 {
+	switch {
+	case timeout <= 0:
+		return 0
+	case timeout > maxRelativeExpiration:
+		return int32(time.Now().Add(timeout).Unix())
+	}
+	return int32((timeout + time.Second - 1) / time.Second)
+}
+
 //Delete delete value in memcache.
 func (mem *Memcache) Delete(key string) error {
-	return mem.conn.Delete(key)
+	if err := mem.conn.Delete(key); err != nil && err != memcache.ErrCacheMiss {
+		return err
+	}
+	return nil
 }
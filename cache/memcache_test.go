@@ -1,28 +1,151 @@
-package cache
+package cache_test
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/cache/cachetest"
 )
 
-func TestMemcache(t *testing.T) {
-	mem := NewMemcache("127.0.0.1:11211")
-	var err error
-	timeoutDuration := 10 * time.Second
-	if err = mem.Set("username", "antsbean", timeoutDuration); err != nil {
-		t.Error("set Error", err)
+//fakeMemcache 实现了 gomemcache 用到的 get、gets、set、delete 命令的 memcached
+type fakeMemcache struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	items map[string]fakeMemcacheItem
+}
+
+type fakeMemcacheItem struct {
+	flags   string
+	value   []byte
+	expired time.Time //零值表示永不过期
+}
+
+func newFakeMemcache(t *testing.T) *fakeMemcache {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
 	}
+	s := &fakeMemcache{ln: ln, items: map[string]fakeMemcacheItem{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeMemcache) Addr() string {
+	return s.ln.Addr().String()
+}
 
-	if !mem.IsExist("username") {
-		t.Error("IsExist Error")
+func (s *fakeMemcache) Close() {
+	s.ln.Close()
+}
+
+func (s *fakeMemcache) serve(conn net.Conn) {
+	defer conn.Close()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		switch args[0] {
+		case "get", "gets":
+			s.get(rw, args[1:])
+		case "set":
+			if err = s.set(rw, args[1:]); err != nil {
+				return
+			}
+		case "delete":
+			s.delete(rw, args[1])
+		default:
+			rw.WriteString("ERROR\r\n")
+		}
+		rw.Flush()
 	}
+}
 
-	name := mem.Get("username").(string)
-	if name != "antsbean" {
-		t.Error("get Error")
+func (s *fakeMemcache) get(rw *bufio.ReadWriter, keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		item, ok := s.items[key]
+		if !ok || (!item.expired.IsZero() && !item.expired.After(time.Now())) {
+			continue
+		}
+		fmt.Fprintf(rw, "VALUE %s %s %d 1\r\n%s\r\n", key, item.flags, len(item.value), item.value)
 	}
+	rw.WriteString("END\r\n")
+}
+
+//set 格式为 set <key> <flags> <exptime> <bytes>, 之后一行是数据
+func (s *fakeMemcache) set(rw *bufio.ReadWriter, args []string) error {
+	exptime, _ := strconv.ParseInt(args[2], 10, 64)
+	size, _ := strconv.Atoi(args[3])
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(rw, data); err != nil {
+		return err
+	}
+
+	item := fakeMemcacheItem{flags: args[1], value: data[:size]}
+	switch {
+	case exptime < 0:
+		item.expired = time.Now()
+	case exptime > 0 && exptime <= 30*24*3600:
+		item.expired = time.Now().Add(time.Duration(exptime) * time.Second)
+	case exptime > 0:
+		item.expired = time.Unix(exptime, 0)
+	}
+	s.mu.Lock()
+	s.items[args[0]] = item
+	s.mu.Unlock()
+	rw.WriteString("STORED\r\n")
+	return nil
+}
 
-	if err = mem.Delete("username"); err != nil {
-		t.Errorf("delete Error , err=%v", err)
+func (s *fakeMemcache) delete(rw *bufio.ReadWriter, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[key]; !ok {
+		rw.WriteString("NOT_FOUND\r\n")
+		return
 	}
+	delete(s.items, key)
+	rw.WriteString("DELETED\r\n")
+}
+
+//memcacheAddr 设置了 WECHAT_TEST_MEMCACHE 时使用该地址的 memcached, 否则启动 fakeMemcache, 测试结束时调用 stop
+func memcacheAddr(t *testing.T) (addr string, stop func()) {
+	if addr := os.Getenv("WECHAT_TEST_MEMCACHE"); addr != "" {
+		return addr, func() {}
+	}
+	s := newFakeMemcache(t)
+	return s.Addr(), s.Close
+}
+
+func TestMemcache(t *testing.T) {
+	addr, stop := memcacheAddr(t)
+	defer stop()
+	cachetest.Run(t, func(t *testing.T) cache.Cache {
+		return cache.NewMemcache(addr)
+	})
 }
@@ -1,74 +1,292 @@
 package cache
 
 import (
+	"container/list"
+	"runtime"
 	"sync"
 	"time"
 )
 
-//Memory struct contains *memcache.Client
+//DefaultCleanupInterval 默认每隔多久清理一次过期的缓存
+const DefaultCleanupInterval = time.Minute
+
+//MemoryOpts 内存缓存的配置
+type MemoryOpts struct {
+	//MaxEntries 最多保存的缓存数量, 超过之后淘汰最久没有访问的缓存, 为 0 时不限制
+	MaxEntries int
+	//CleanupInterval 后台清理过期缓存的间隔, 为 0 时使用 DefaultCleanupInterval, 小于 0 时不启动后台清理
+	CleanupInterval time.Duration
+}
+
+//MemoryStats 内存缓存的统计信息
+type MemoryStats struct {
+	Hits        uint64 //Get 命中的次数
+	Misses      uint64 //Get 没有命中(不存在或者已过期)的次数
+	Evictions   uint64 //超过 MaxEntries 被淘汰的缓存数量
+	Expirations uint64 //过期之后被删除的缓存数量
+	Entries     int    //当前保存的缓存数量, 包含已过期但还没有被清理的缓存
+}
+
+//Memory 进程内的缓存, 可以在多个 goroutine 中并发使用
 type Memory struct {
+	*memory
+}
+
+//memory 后台清理的 goroutine 只引用 memory, Memory 不再被使用时可以被回收并停止后台清理
+type memory struct {
 	sync.Mutex
 
-	data map[string]*data
+	data       map[string]*data
+	lru        *list.List //最近访问的缓存在最前面
+	maxEntries int
+
+	//locks TryLock 获取的锁, 与缓存分开保存, 不会被 maxEntries 淘汰, Get、IsExist 也不可见
+	locks map[string]*data
+
+	//统计信息, 调用方需要持有锁
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+	expirations uint64
+
+	stop      chan struct{}
+	closeOnce sync.Once
 }
 
 type data struct {
 	Data    interface{}
-	Expired time.Time
+	Expired time.Time //零值表示永不过期
+
+	key  string
+	elem *list.Element
 }
 
-//NewMemory create new memcache
+func (d *data) expired(now time.Time) bool {
+	return !d.Expired.IsZero() && !d.Expired.After(now)
+}
+
+//NewMemory 实例化, 不限制缓存数量, 每隔 DefaultCleanupInterval 清理一次过期的缓存
 func NewMemory() *Memory {
-	return &Memory{
-		data: map[string]*data{},
+	return NewMemoryWithOpts(&MemoryOpts{})
+}
+
+//NewMemoryWithOpts 根据配置实例化
+func NewMemoryWithOpts(opts *MemoryOpts) *Memory {
+	m := &memory{
+		data:       map[string]*data{},
+		lru:        list.New(),
+		maxEntries: opts.MaxEntries,
+		locks:      map[string]*data{},
+		stop:       make(chan struct{}),
+	}
+	mem := &Memory{m}
+
+	interval := opts.CleanupInterval
+	if interval == 0 {
+		interval = DefaultCleanupInterval
+	}
+	if interval > 0 {
+		go m.janitor(interval)
+		runtime.SetFinalizer(mem, func(mem *Memory) {
+			mem.Close()
+		})
 	}
+	return mem
 }
 
-//Get return cached value
-func (mem *Memory) Get(key string) interface{} {
-	if ret, ok := mem.data[key]; ok {
-		if ret.Expired.Before(time.Now()) {
-			mem.deleteKey(key)
-			return nil
+//janitor 定期清理过期的缓存, 直到调用 Close
+func (mem *memory) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			mem.DeleteExpired()
+		case <-mem.stop:
+			return
 		}
-		return ret.Data
 	}
+}
+
+//Close 停止后台清理, 已保存的缓存仍然可以使用
+func (mem *memory) Close() error {
+	mem.closeOnce.Do(func() {
+		close(mem.stop)
+	})
 	return nil
 }
 
-// IsExist check value exists in memcache.
-func (mem *Memory) IsExist(key string) bool {
-	if ret, ok := mem.data[key]; ok {
-		if ret.Expired.Before(time.Now()) {
-			mem.deleteKey(key)
-			return false
-		}
-		return true
+//Get return cached value
+func (mem *memory) Get(key string) interface{} {
+	mem.Lock()
+	defer mem.Unlock()
+
+	ret, ok := mem.data[key]
+	if !ok {
+		mem.misses++
+		return nil
+	}
+	if ret.expired(time.Now()) {
+		mem.removeExpired(ret)
+		mem.misses++
+		return nil
 	}
-	return false
+	mem.lru.MoveToFront(ret.elem)
+	mem.hits++
+	return ret.Data
 }
 
-//Set cached value with key and expire time.
-func (mem *Memory) Set(key string, val interface{}, timeout time.Duration) (err error) {
+//GetInto 获取 key 对应的值并保存到 val 指向的变量中, 实现 TypedGetter 接口
+//保存的值可以直接赋值给 *val 时直接赋值(与保存的值共享 map、slice 等), 否则通过 json 转换
+func (mem *memory) GetInto(key string, val interface{}) error {
+	return assign(mem.Get(key), val)
+}
+
+// IsExist check value exists in memory.
+func (mem *memory) IsExist(key string) bool {
 	mem.Lock()
 	defer mem.Unlock()
 
-	mem.data[key] = &data{
-		Data:    val,
-		Expired: time.Now().Add(timeout),
+	ret, ok := mem.data[key]
+	if !ok {
+		return false
+	}
+	if ret.expired(time.Now()) {
+		mem.removeExpired(ret)
+		return false
 	}
+	return true
+}
+
+//TTL 返回 key 的剩余有效期, 实现 TTLGetter 接口
+func (mem *memory) TTL(key string) (time.Duration, error) {
+	mem.Lock()
+	defer mem.Unlock()
+
+	ret, ok := mem.data[key]
+	now := time.Now()
+	if !ok || ret.expired(now) {
+		return 0, ErrCacheMiss
+	}
+	if ret.Expired.IsZero() {
+		return 0, nil
+	}
+	return ret.Expired.Sub(now), nil
+}
+
+//Set cached value with key and expire time, timeout 小于等于 0 时永不过期
+func (mem *memory) Set(key string, val interface{}, timeout time.Duration) (err error) {
+	mem.Lock()
+	defer mem.Unlock()
+
+	var expired time.Time
+	if timeout > 0 {
+		expired = time.Now().Add(timeout)
+	}
+	mem.set(key, &data{Data: val, Expired: expired})
 	return nil
 }
 
-//Delete delete value in memcache.
-func (mem *Memory) Delete(key string) error {
-	return mem.deleteKey(key)
+//set 保存缓存并淘汰超出 maxEntries 的缓存, 调用方需要持有锁
+func (mem *memory) set(key string, d *data) {
+	d.key = key
+	if old, ok := mem.data[key]; ok {
+		mem.lru.Remove(old.elem)
+	}
+	d.elem = mem.lru.PushFront(d)
+	mem.data[key] = d
+
+	if mem.maxEntries <= 0 {
+		return
+	}
+	for mem.lru.Len() > mem.maxEntries {
+		oldest := mem.lru.Back().Value.(*data)
+		mem.remove(oldest)
+		mem.evictions++
+	}
 }
 
-// deleteKey
-func (mem *Memory) deleteKey(key string) error {
+//Delete delete value in memory.
+func (mem *memory) Delete(key string) error {
 	mem.Lock()
 	defer mem.Unlock()
-	delete(mem.data, key)
+
+	if ret, ok := mem.data[key]; ok {
+		mem.remove(ret)
+	}
 	return nil
 }
+
+//DeleteExpired 删除所有过期的缓存以及过期的锁, 后台清理时调用
+func (mem *memory) DeleteExpired() {
+	mem.Lock()
+	defer mem.Unlock()
+
+	now := time.Now()
+	for _, ret := range mem.data {
+		if ret.expired(now) {
+			mem.removeExpired(ret)
+		}
+	}
+	for key, lock := range mem.locks {
+		if lock.expired(now) {
+			delete(mem.locks, key)
+		}
+	}
+}
+
+//Len 当前保存的缓存数量, 包含已过期但还没有被清理的缓存
+func (mem *memory) Len() int {
+	mem.Lock()
+	defer mem.Unlock()
+	return len(mem.data)
+}
+
+//Stats 返回命中率等统计信息
+func (mem *memory) Stats() MemoryStats {
+	mem.Lock()
+	defer mem.Unlock()
+	return MemoryStats{
+		Hits:        mem.hits,
+		Misses:      mem.misses,
+		Evictions:   mem.evictions,
+		Expirations: mem.expirations,
+		Entries:     len(mem.data),
+	}
+}
+
+//remove 删除缓存, 调用方需要持有锁
+func (mem *memory) remove(d *data) {
+	mem.lru.Remove(d.elem)
+	delete(mem.data, d.key)
+}
+
+//removeExpired 删除过期的缓存并计数, 调用方需要持有锁
+func (mem *memory) removeExpired(d *data) {
+	mem.remove(d)
+	mem.expirations++
+}
+
+//TryLock 进程内的锁，实现 Locker 接口
+func (mem *memory) TryLock(key string, ttl time.Duration) (unlock func() error, ok bool, err error) {
+	mem.Lock()
+	defer mem.Unlock()
+
+	now := time.Now()
+	if held, exists := mem.locks[key]; exists && !held.expired(now) {
+		return
+	}
+	lock := &data{Expired: now.Add(ttl)}
+	mem.locks[key] = lock
+
+	unlock = func() error {
+		mem.Lock()
+		defer mem.Unlock()
+		//锁过期后可能已经被其它调用方获取
+		if mem.locks[key] == lock {
+			delete(mem.locks, key)
+		}
+		return nil
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryTryLock(t *testing.T) {
+	mem := NewMemory()
+
+	unlock, ok, err := mem.TryLock("lock", time.Minute)
+	if !ok || err != nil {
+		t.Fatalf("expect lock obtained, err=%v", err)
+	}
+	if _, ok, _ := mem.TryLock("lock", time.Minute); ok {
+		t.Error("expect lock held by others")
+	}
+	if err = unlock(); err != nil {
+		t.Errorf("unlock Error , err=%v", err)
+	}
+	if _, ok, _ := mem.TryLock("lock", time.Millisecond); !ok {
+		t.Error("expect lock obtained after unlock")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, ok, _ := mem.TryLock("lock", time.Minute); !ok {
+		t.Error("expect lock obtained after expired")
+	}
+}
+
+func TestMemoryTryLockSeparateFromEntries(t *testing.T) {
+	mem := NewMemoryWithOpts(&MemoryOpts{MaxEntries: 1, CleanupInterval: -1})
+
+	if _, ok, _ := mem.TryLock("lock", time.Minute); !ok {
+		t.Fatal("expect lock obtained")
+	}
+	//缓存超过 MaxEntries 时不能淘汰持有的锁
+	mem.Set("a", 1, time.Minute)
+	mem.Set("b", 2, time.Minute)
+	if _, ok, _ := mem.TryLock("lock", time.Minute); ok {
+		t.Error("expect lock still held after evictions")
+	}
+	if mem.IsExist("lock") || mem.Get("lock") != nil || mem.Len() != 1 {
+		t.Errorf("expect lock invisible to cache entries, len=%d", mem.Len())
+	}
+	//与锁同名的缓存不影响锁
+	mem.Set("lock", "value", time.Minute)
+	mem.Delete("lock")
+	if _, ok, _ := mem.TryLock("lock", time.Minute); ok {
+		t.Error("expect lock held after Set/Delete of the same key")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	mem := NewMemory()
+	defer mem.Close()
+
+	if err := mem.Set("username", "antsbean", time.Minute); err != nil {
+		t.Error("set Error", err)
+	}
+	if !mem.IsExist("username") {
+		t.Error("IsExist Error")
+	}
+	if name, _ := mem.Get("username").(string); name != "antsbean" {
+		t.Error("get Error")
+	}
+	if err := mem.Delete("username"); err != nil {
+		t.Errorf("delete Error , err=%v", err)
+	}
+	if mem.IsExist("username") || mem.Get("username") != nil {
+		t.Error("expect deleted")
+	}
+
+	if err := mem.Set("forever", "antsbean", 0); err != nil {
+		t.Error("set Error", err)
+	}
+	if !mem.IsExist("forever") {
+		t.Error("expect value without timeout never expired")
+	}
+}
+
+func TestMemoryJanitor(t *testing.T) {
+	mem := NewMemoryWithOpts(&MemoryOpts{CleanupInterval: 5 * time.Millisecond})
+	defer mem.Close()
+
+	mem.Set("expired", "antsbean", time.Millisecond)
+	mem.Set("alive", "antsbean", time.Minute)
+	deadline := time.Now().Add(time.Second)
+	for mem.Len() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := mem.Len(); n != 1 {
+		t.Fatalf("expect expired value removed by janitor, len=%d", n)
+	}
+	if stats := mem.Stats(); stats.Expirations != 1 {
+		t.Errorf("expect 1 expiration, got %+v", stats)
+	}
+}
+
+func TestMemoryMaxEntries(t *testing.T) {
+	mem := NewMemoryWithOpts(&MemoryOpts{MaxEntries: 2, CleanupInterval: -1})
+
+	mem.Set("a", 1, time.Minute)
+	mem.Set("b", 2, time.Minute)
+	//访问 a 之后 b 成为最久没有访问的缓存
+	mem.Get("a")
+	mem.Set("c", 3, time.Minute)
+
+	if mem.IsExist("b") {
+		t.Error("expect b evicted")
+	}
+	if !mem.IsExist("a") || !mem.IsExist("c") {
+		t.Error("expect a and c kept")
+	}
+	mem.Get("b")
+	stats := mem.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+//TestMemoryConcurrent 需要使用 go test -race 运行
+func TestMemoryConcurrent(t *testing.T) {
+	mem := NewMemoryWithOpts(&MemoryOpts{MaxEntries: 64, CleanupInterval: time.Millisecond})
+	defer mem.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				key := fmt.Sprintf("key%d", (i*j)%100)
+				switch j % 5 {
+				case 0:
+					mem.Set(key, j, time.Duration(j%3)*time.Millisecond)
+				case 1:
+					mem.Get(key)
+				case 2:
+					mem.IsExist(key)
+				case 3:
+					mem.Delete(key)
+				case 4:
+					if unlock, ok, _ := mem.TryLock("lock"+key, time.Millisecond); ok {
+						unlock()
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := mem.Len(); n > 64 {
+		t.Errorf("expect at most 64 entries, got %d", n)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/antsbean/wechat/util"
+
 	"github.com/gomodule/redigo/redis"
 )
 
@@ -50,6 +52,11 @@ func (r *Redis) SetConn(conn *redis.Pool) {
 	r.conn = conn
 }
 
+//Close 关闭连接池
+func (r *Redis) Close() error {
+	return r.conn.Close()
+}
+
 //Get 获取一个值
 func (r *Redis) Get(key string) interface{} {
 	conn := r.conn.Get()
@@ -68,7 +75,22 @@ func (r *Redis) Get(key string) interface{} {
 	return reply
 }
 
-//Set 设置一个值
+//GetInto 获取一个值并解析到 val 指向的变量中, 实现 TypedGetter 接口
+func (r *Redis) GetInto(key string, val interface{}) error {
+	conn := r.conn.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	return unmarshal(data, val)
+}
+
+//Set 设置一个值, timeout 小于等于 0 时永不过期
 func (r *Redis) Set(key string, val interface{}, timeout time.Duration) (err error) {
 	conn := r.conn.Get()
 	defer conn.Close()
@@ -78,22 +100,43 @@ func (r *Redis) Set(key string, val interface{}, timeout time.Duration) (err err
 		return
 	}
 
-	_, err = conn.Do("SETEX", key, int64(timeout/time.Second), data)
+	switch {
+	case timeout <= 0:
+		_, err = conn.Do("SET", key, data)
+	case timeout%time.Second != 0:
+		_, err = conn.Do("SET", key, data, "PX", redisMilliseconds(timeout))
+	default:
+		_, err = conn.Do("SETEX", key, int64(timeout/time.Second), data)
+	}
 
 	return
 }
 
+//redisMilliseconds 转换为 PX 的毫秒数, 不足一毫秒按一毫秒处理
+//向上取整保证不会比 timeout 更早过期, 同时避免 PX 0 被 Redis 拒绝
+func redisMilliseconds(timeout time.Duration) int64 {
+	return int64((timeout + time.Millisecond - 1) / time.Millisecond)
+}
+
+//TTL 返回 key 的剩余有效期, 实现 TTLGetter 接口
+func (r *Redis) TTL(key string) (time.Duration, error) {
+	conn := r.conn.Get()
+	defer conn.Close()
+
+	ms, err := redis.Int64(conn.Do("PTTL", key))
+	if err != nil {
+		return 0, err
+	}
+	return pttl(ms)
+}
+
 //IsExist 判断key是否存在
 func (r *Redis) IsExist(key string) bool {
 	conn := r.conn.Get()
 	defer conn.Close()
 
-	a, _ := conn.Do("EXISTS", key)
-	i := a.(int64)
-	if i > 0 {
-		return true
-	}
-	return false
+	i, err := redis.Int64(conn.Do("EXISTS", key))
+	return err == nil && i > 0
 }
 
 //Delete 删除
@@ -107,3 +150,81 @@ func (r *Redis) Delete(key string) error {
 
 	return nil
 }
+
+//unlockScript 只有锁的持有者才能删除锁
+var unlockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+//TryLock 通过 SET NX PX 获取锁，实现 Locker 接口
+func (r *Redis) TryLock(key string, ttl time.Duration) (unlock func() error, ok bool, err error) {
+	conn := r.conn.Get()
+	defer conn.Close()
+
+	token := util.RandomStr(32)
+	var reply interface{}
+	reply, err = conn.Do("SET", key, token, "NX", "PX", int64(ttl/time.Millisecond))
+	if err != nil || reply == nil {
+		return
+	}
+
+	unlock = func() error {
+		conn := r.conn.Get()
+		defer conn.Close()
+
+		_, err := unlockScript.Do(conn, key, token)
+		return err
+	}
+	return unlock, true, nil
+}
+
+//Invalidator 通过 Redis 的 pub/sub 在多个进程之间广播需要删除本地缓存的 key, 用于 Tiered
+//订阅的连接断开之后不会重新订阅, 本地缓存仍然会在 TieredOpts.LocalTTL 之后过期
+func (r *Redis) Invalidator(channel string) Invalidator {
+	return &redisInvalidator{r: r, channel: channel}
+}
+
+type redisInvalidator struct {
+	r       *Redis
+	channel string
+}
+
+func (i *redisInvalidator) Publish(key string) error {
+	conn := i.r.conn.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("PUBLISH", i.channel, key)
+	return err
+}
+
+func (i *redisInvalidator) Subscribe(fn func(key string)) (unsubscribe func() error, err error) {
+	//使用单独的连接, 关闭时直接断开, 不归还到连接池
+	conn, err := i.r.conn.Dial()
+	if err != nil {
+		return nil, err
+	}
+	psc := redis.PubSubConn{Conn: conn}
+	if err = psc.Subscribe(i.channel); err != nil {
+		psc.Close()
+		return nil, err
+	}
+	//等待订阅成功, 之后发布的消息都可以收到
+	if v, ok := psc.Receive().(error); ok {
+		psc.Close()
+		return nil, v
+	}
+	go func() {
+		for {
+			switch v := psc.Receive().(type) {
+			case redis.Message:
+				fn(string(v.Data))
+			case error:
+				return
+			}
+		}
+	}()
+	return psc.Close, nil
+}
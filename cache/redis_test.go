@@ -1,32 +1,103 @@
-package cache
+package cache_test
 
 import (
+	"os"
 	"testing"
 	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/cache/cachetest"
 )
 
-func TestRedis(t *testing.T) {
-	opts := &RedisOpts{
-		Host: "127.0.0.1:6379",
+//redisAddr 设置了 WECHAT_TEST_REDIS 时使用该地址的 Redis, 否则启动 miniredis, 测试结束时调用 stop
+func redisAddr(t *testing.T) (string, func()) {
+	if addr := os.Getenv("WECHAT_TEST_REDIS"); addr != "" {
+		return addr, func() {}
 	}
-	redis := NewRedis(opts)
-	var err error
-	timeoutDuration := 1 * time.Second
-
-	if err = redis.Set("username", "antsbean", timeoutDuration); err != nil {
-		t.Error("set Error", err)
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
 	}
-
-	if !redis.IsExist("username") {
-		t.Error("IsExist Error")
+	//miniredis 中的 key 只有调用 FastForward 时才会过期
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.FastForward(10 * time.Millisecond)
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return s.Addr(), func() {
+		close(stop)
+		s.Close()
 	}
+}
 
-	name := redis.Get("username").(string)
-	if name != "antsbean" {
-		t.Error("get Error")
-	}
+func TestRedis(t *testing.T) {
+	addr, stop := redisAddr(t)
+	defer stop()
+	var caches closers
+	defer caches.close()
+	cachetest.Run(t, func(t *testing.T) cache.Cache {
+		r := cache.NewRedis(&cache.RedisOpts{Host: addr})
+		caches = append(caches, r.Close)
+		return r
+	})
+}
 
-	if err = redis.Delete("username"); err != nil {
-		t.Errorf("delete Error , err=%v", err)
+func TestRedisTryLock(t *testing.T) {
+	addr, stop := redisAddr(t)
+	defer stop()
+	redis := cache.NewRedis(&cache.RedisOpts{Host: addr})
+	defer redis.Close()
+
+	unlock, ok, err := redis.TryLock("lock", 10*time.Second)
+	if !ok || err != nil {
+		t.Fatalf("TryLock Error , ok=%v , err=%v", ok, err)
+	}
+	if _, ok, _ = redis.TryLock("lock", 10*time.Second); ok {
+		t.Error("expect lock held by others")
+	}
+	if err = unlock(); err != nil {
+		t.Errorf("unlock Error , err=%v", err)
+	}
+	if redis.IsExist("lock") {
+		t.Error("expect lock released")
 	}
 }
+
+func TestGoRedisConformance(t *testing.T) {
+	addr, stop := redisAddr(t)
+	defer stop()
+	var caches closers
+	defer caches.close()
+	cachetest.Run(t, func(t *testing.T) cache.Cache {
+		r := cache.NewGoRedis(&cache.GoRedisOpts{Addrs: []string{addr}, KeyPrefix: "cachetest:"})
+		caches = append(caches, r.Close)
+		return r
+	})
+}
+
+func TestTieredConformance(t *testing.T) {
+	addr, stop := redisAddr(t)
+	defer stop()
+	var caches closers
+	defer caches.close()
+	cachetest.Run(t, func(t *testing.T) cache.Cache {
+		r := cache.NewGoRedis(&cache.GoRedisOpts{Addrs: []string{addr}, KeyPrefix: "cachetest:tiered:"})
+		tiered, err := cache.NewTiered(r, &cache.TieredOpts{Invalidator: r.Invalidator("invalidate")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		//先关闭 Tiered 再关闭 Redis
+		caches = append(caches, tiered.Close, r.Close)
+		return tiered
+	})
+}
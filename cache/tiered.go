@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+//DefaultLocalTTL Tiered 中本地缓存默认的有效期
+const DefaultLocalTTL = 10 * time.Second
+
+//ErrTTLUnsupported 远程缓存没有实现 TTLGetter, 且没有设置 TieredOpts.AllowUnknownTTL
+var ErrTTLUnsupported = errors.New("cache: remote cache does not implement TTLGetter")
+
+//TTLGetter 可以获取缓存剩余有效期的 Cache, Memory、Redis、GoRedis 都实现了该接口
+type TTLGetter interface {
+	//TTL 返回 key 的剩余有效期, key 不存在时返回 ErrCacheMiss, 永不过期时返回 0
+	TTL(key string) (time.Duration, error)
+}
+
+//Invalidator 在多个进程之间广播需要删除本地缓存的 key
+type Invalidator interface {
+	//Publish 通知所有进程删除 key 对应的本地缓存
+	Publish(key string) error
+	//Subscribe 订阅其它进程发布的 key, unsubscribe 用于取消订阅
+	Subscribe(fn func(key string)) (unsubscribe func() error, err error)
+}
+
+//TieredOpts 两级缓存的配置
+type TieredOpts struct {
+	//LocalTTL 本地缓存的最长有效期, 同时不会超过远程缓存的剩余有效期, 为 0 时使用 DefaultLocalTTL
+	LocalTTL time.Duration
+	//AllowUnknownTTL 远程缓存没有实现 TTLGetter(如 Memcache)时, 仍然在本地保存 LocalTTL,
+	//远程缓存中的值过期之后, 本地最多还会返回 LocalTTL 的旧值; 为 false 时 NewTiered 返回 ErrTTLUnsupported
+	AllowUnknownTTL bool
+	//MaxEntries 本地最多保存的缓存数量, 为 0 时不限制
+	MaxEntries int
+	//Invalidator 不为空时, Set、Delete 会通知其它进程删除本地缓存, 例如 GoRedis.Invalidator("wechat:invalidate")
+	Invalidator Invalidator
+}
+
+//Tiered 两级缓存, 在 Redis 等远程缓存之前保存一份短期的本地缓存, 减少 GetAccessToken 等调用的网络请求
+//Set、Delete 直接修改远程缓存并删除本地缓存; 没有设置 Invalidator 时, 其它进程最多在 LocalTTL 之后读到新值
+//远程缓存需要实现 TTLGetter, 否则需要设置 TieredOpts.AllowUnknownTTL
+//与 Redis 相同, 本地以 json 保存, Get 返回 json 解析之后的值, 需要保持类型时使用 GetInto
+type Tiered struct {
+	remote   Cache
+	local    *Memory
+	localTTL time.Duration
+
+	invalidator Invalidator
+	unsubscribe func() error
+
+	//mu 保证删除本地缓存之后, 不会再保存删除之前从远程读取的值
+	mu         sync.Mutex
+	generation uint64
+}
+
+//NewTiered 实例化, 设置了 Invalidator 时订阅其它进程的通知, 订阅失败时返回 error
+//remote 没有实现 TTLGetter 且没有设置 AllowUnknownTTL 时返回 ErrTTLUnsupported
+func NewTiered(remote Cache, opts *TieredOpts) (*Tiered, error) {
+	if _, ok := remote.(TTLGetter); !ok && !opts.AllowUnknownTTL {
+		return nil, ErrTTLUnsupported
+	}
+	localTTL := opts.LocalTTL
+	if localTTL <= 0 {
+		localTTL = DefaultLocalTTL
+	}
+	t := &Tiered{
+		remote:      remote,
+		local:       NewMemoryWithOpts(&MemoryOpts{MaxEntries: opts.MaxEntries}),
+		localTTL:    localTTL,
+		invalidator: opts.Invalidator,
+	}
+	if t.invalidator != nil {
+		unsubscribe, err := t.invalidator.Subscribe(t.evict)
+		if err != nil {
+			t.local.Close()
+			return nil, err
+		}
+		t.unsubscribe = unsubscribe
+	}
+	return t, nil
+}
+
+//Local 返回本地缓存, 可以通过 Local().Stats() 获取命中率
+func (t *Tiered) Local() *Memory {
+	return t.local
+}
+
+//Remote 返回远程缓存
+func (t *Tiered) Remote() Cache {
+	return t.remote
+}
+
+//Close 取消订阅并停止本地缓存的后台清理, 不会关闭远程缓存
+func (t *Tiered) Close() error {
+	var err error
+	if t.unsubscribe != nil {
+		err = t.unsubscribe()
+	}
+	t.local.Close()
+	return err
+}
+
+//Get 获取一个值, 本地没有时从远程缓存获取并保存到本地
+func (t *Tiered) Get(key string) interface{} {
+	var reply interface{}
+	if err := t.GetInto(key, &reply); err != nil {
+		return nil
+	}
+	return reply
+}
+
+//GetInto 获取一个值并解析到 val 指向的变量中, 实现 TypedGetter 接口
+func (t *Tiered) GetInto(key string, val interface{}) error {
+	data, err := t.fetch(key)
+	if err != nil {
+		return err
+	}
+	return unmarshal(data, val)
+}
+
+//fetch 获取 key 对应的 json
+func (t *Tiered) fetch(key string) (json.RawMessage, error) {
+	if data, ok := t.local.Get(key).(json.RawMessage); ok {
+		return data, nil
+	}
+
+	t.mu.Lock()
+	generation := t.generation
+	t.mu.Unlock()
+
+	var data json.RawMessage
+	if err := GetInto(t.remote, key, &data); err != nil {
+		return nil, err
+	}
+	ttl := t.localTTL
+	//没有实现 TTLGetter 时(设置了 AllowUnknownTTL)直接保存 LocalTTL
+	if getter, ok := t.remote.(TTLGetter); ok {
+		remoteTTL, err := getter.TTL(key)
+		if err != nil {
+			//获取不到剩余有效期时不保存到本地, 避免本地缓存比远程缓存更晚过期
+			return data, nil
+		}
+		if remoteTTL > 0 && remoteTTL < ttl {
+			ttl = remoteTTL
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	//读取远程缓存的过程中 key 可能已经被修改
+	if t.generation == generation {
+		t.local.Set(key, data, ttl)
+	}
+	return data, nil
+}
+
+//Set 设置远程缓存并删除本地缓存
+func (t *Tiered) Set(key string, val interface{}, timeout time.Duration) error {
+	err := t.remote.Set(key, val, timeout)
+	t.invalidate(key)
+	return err
+}
+
+//IsExist 判断key是否存在
+func (t *Tiered) IsExist(key string) bool {
+	return t.local.IsExist(key) || t.remote.IsExist(key)
+}
+
+//Delete 删除远程缓存以及本地缓存
+func (t *Tiered) Delete(key string) error {
+	err := t.remote.Delete(key)
+	t.invalidate(key)
+	return err
+}
+
+//invalidate 删除本地缓存并通知其它进程
+func (t *Tiered) invalidate(key string) {
+	t.evict(key)
+	if t.invalidator != nil {
+		//通知失败时其它进程的本地缓存仍然会在 LocalTTL 之后过期
+		t.invalidator.Publish(key)
+	}
+}
+
+//evict 删除本地缓存
+func (t *Tiered) evict(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.generation++
+	t.local.Delete(key)
+}
+
+//pttl 转换 Redis PTTL 命令的返回值, -2 表示 key 不存在, -1 表示永不过期
+func pttl(ms int64) (time.Duration, error) {
+	switch {
+	case ms == -2:
+		return 0, ErrCacheMiss
+	case ms < 0:
+		return 0, nil
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
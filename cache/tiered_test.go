@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+//countingCache 记录读取远程缓存的次数
+type countingCache struct {
+	*Memory
+	gets int32
+}
+
+func (c *countingCache) GetInto(key string, val interface{}) error {
+	atomic.AddInt32(&c.gets, 1)
+	return c.Memory.GetInto(key, val)
+}
+
+func TestTiered(t *testing.T) {
+	remote := &countingCache{Memory: NewMemory()}
+	tiered, err := NewTiered(remote, &TieredOpts{LocalTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tiered.Close()
+
+	if err = tiered.Set("username", "antsbean", time.Minute); err != nil {
+		t.Fatal("set Error", err)
+	}
+	for i := 0; i < 3; i++ {
+		if name, _ := tiered.Get("username").(string); name != "antsbean" {
+			t.Error("get Error")
+		}
+	}
+	if gets := atomic.LoadInt32(&remote.gets); gets != 1 {
+		t.Errorf("expect remote get once, got %d", gets)
+	}
+
+	//Set 之后删除本地缓存
+	tiered.Set("username", "wechat", time.Minute)
+	if name, _ := tiered.Get("username").(string); name != "wechat" {
+		t.Errorf("expect new value after set, got %s", name)
+	}
+
+	tiered.Set("struct", typedValue{Name: "antsbean", Count: 42}, time.Minute)
+	var v typedValue
+	if err = tiered.GetInto("struct", &v); err != nil || v.Count != 42 {
+		t.Errorf("GetInto got %+v, err=%v", v, err)
+	}
+
+	if err = tiered.Delete("username"); err != nil {
+		t.Errorf("delete Error , err=%v", err)
+	}
+	if tiered.IsExist("username") || tiered.Get("username") != nil {
+		t.Error("expect deleted")
+	}
+	if err = tiered.GetInto("username", &v); err != ErrCacheMiss {
+		t.Errorf("expect ErrCacheMiss, got %v", err)
+	}
+}
+
+func TestTieredRemoteTTL(t *testing.T) {
+	remote := NewMemory()
+	tiered, err := NewTiered(remote, &TieredOpts{LocalTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tiered.Close()
+
+	remote.Set("token", "antsbean", 10*time.Second)
+	tiered.Get("token")
+	ttl, err := tiered.Local().TTL("token")
+	if err != nil || ttl <= 0 || ttl > 10*time.Second {
+		t.Errorf("expect local ttl bounded by remote ttl, got %v, err=%v", ttl, err)
+	}
+}
+
+func TestTieredRemoteWithoutTTL(t *testing.T) {
+	//jsonCache 没有实现 TTLGetter, 无法保证本地缓存不比远程缓存晚过期
+	remote := &jsonCache{mem: NewMemory()}
+	if _, err := NewTiered(remote, &TieredOpts{LocalTTL: time.Minute}); err != ErrTTLUnsupported {
+		t.Fatalf("expect ErrTTLUnsupported, got %v", err)
+	}
+
+	tiered, err := NewTiered(remote, &TieredOpts{LocalTTL: time.Minute, AllowUnknownTTL: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tiered.Close()
+
+	remote.Set("token", "antsbean", 10*time.Second)
+	if name, _ := tiered.Get("token").(string); name != "antsbean" {
+		t.Errorf("get Error, got %s", name)
+	}
+	ttl, err := tiered.Local().TTL("token")
+	if err != nil || ttl <= 10*time.Second || ttl > time.Minute {
+		t.Errorf("expect local ttl up to LocalTTL, got %v, err=%v", ttl, err)
+	}
+}
+
+func TestTieredInvalidator(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	goRedis := NewGoRedis(&GoRedisOpts{Addrs: []string{s.Addr()}, KeyPrefix: "test:"})
+	defer goRedis.Close()
+	redis := NewRedis(&RedisOpts{Host: s.Addr()})
+	defer redis.Close()
+
+	remotes := map[string]struct {
+		remote      Cache
+		invalidator func() Invalidator
+	}{
+		"goredis": {goRedis, func() Invalidator { return goRedis.Invalidator("invalidate") }},
+		"redis":   {redis, func() Invalidator { return redis.Invalidator("invalidate") }},
+	}
+	for name, r := range remotes {
+		//两个进程共享同一个 Redis
+		var nodes []*Tiered
+		for i := 0; i < 2; i++ {
+			tiered, err := NewTiered(r.remote, &TieredOpts{LocalTTL: time.Minute, Invalidator: r.invalidator()})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			defer tiered.Close()
+			nodes = append(nodes, tiered)
+		}
+
+		nodes[0].Set("token", "old", time.Minute)
+		if val, _ := nodes[0].Get("token").(string); val != "old" {
+			t.Errorf("%s: get Error", name)
+		}
+		nodes[1].Set("token", "new", time.Minute)
+
+		deadline := time.Now().Add(time.Second)
+		for nodes[0].Local().IsExist("token") && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if val, _ := nodes[0].Get("token").(string); val != "new" {
+			t.Errorf("%s: expect local cache invalidated, got %s", name, val)
+		}
+	}
+}
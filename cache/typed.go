@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+//ErrCacheMiss key 不存在或者已经过期
+var ErrCacheMiss = errors.New("cache: key not found")
+
+//TypedGetter 可以把缓存的值解析为指定类型的 Cache, Memory、Redis、Memcache 都实现了该接口
+//Redis、Memcache 的 Get 把 json 解析为 interface{}, 结构体会变为 map[string]interface{}、数字会变为 float64,
+//需要保持类型时应该使用 GetInto
+type TypedGetter interface {
+	//GetInto 获取 key 对应的值并保存到 val 指向的变量中, key 不存在时返回 ErrCacheMiss
+	GetInto(key string, val interface{}) error
+}
+
+//GetInto 获取 key 对应的值并保存到 val 指向的变量中, key 不存在时返回 ErrCacheMiss
+//c 没有实现 TypedGetter 时, Get 返回的值可以直接赋值给 *val 时直接赋值, 否则通过 json 转换
+func GetInto(c Cache, key string, val interface{}) error {
+	if getter, ok := c.(TypedGetter); ok {
+		return getter.GetInto(key, val)
+	}
+	return assign(c.Get(key), val)
+}
+
+//GetString 获取 key 对应的字符串, key 不存在或者不是字符串时 ok 为 false
+func GetString(c Cache, key string) (val string, ok bool) {
+	if err := GetInto(c, key, &val); err != nil {
+		return "", false
+	}
+	return val, true
+}
+
+//GetInt64 获取 key 对应的整数, key 不存在或者不是整数时 ok 为 false
+func GetInt64(c Cache, key string) (val int64, ok bool) {
+	if err := GetInto(c, key, &val); err != nil {
+		return 0, false
+	}
+	return val, true
+}
+
+//assign 把 src 保存到 dst 指向的变量中, 类型不同时通过 json 转换, 与 Redis、Memcache 的行为保持一致
+func assign(src interface{}, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cache: GetInto(non-pointer %T)", dst)
+	}
+	if src == nil {
+		return ErrCacheMiss
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(rv.Elem().Type()) {
+		rv.Elem().Set(sv)
+		return nil
+	}
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+//unmarshal 解析 Redis、Memcache 中保存的 json
+func unmarshal(data []byte, val interface{}) error {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cache: GetInto(non-pointer %T)", val)
+	}
+	return json.Unmarshal(data, val)
+}
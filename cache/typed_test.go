@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type typedValue struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+//jsonCache 与 Redis 相同, 保存 json 并在 Get 时解析为 interface{}, 没有实现 TypedGetter
+type jsonCache struct {
+	mem *Memory
+}
+
+func (c *jsonCache) Get(key string) interface{} {
+	data, ok := c.mem.Get(key).([]byte)
+	if !ok {
+		return nil
+	}
+	var reply interface{}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil
+	}
+	return reply
+}
+
+func (c *jsonCache) Set(key string, val interface{}, timeout time.Duration) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return c.mem.Set(key, data, timeout)
+}
+
+func (c *jsonCache) IsExist(key string) bool {
+	return c.mem.IsExist(key)
+}
+
+func (c *jsonCache) Delete(key string) error {
+	return c.mem.Delete(key)
+}
+
+func TestGetInto(t *testing.T) {
+	caches := map[string]Cache{
+		"memory": NewMemory(),
+		"json":   &jsonCache{mem: NewMemory()},
+	}
+	for name, c := range caches {
+		want := typedValue{Name: "antsbean", Count: 42}
+		c.Set("struct", want, time.Minute)
+		c.Set("string", "antsbean", time.Minute)
+		c.Set("int", 42, time.Minute)
+
+		var got typedValue
+		if err := GetInto(c, "struct", &got); err != nil || got != want {
+			t.Errorf("%s: GetInto struct got %+v, err=%v", name, got, err)
+		}
+		var ptr *typedValue
+		if err := GetInto(c, "struct", &ptr); err != nil || ptr == nil || *ptr != want {
+			t.Errorf("%s: GetInto pointer got %+v, err=%v", name, ptr, err)
+		}
+		if s, ok := GetString(c, "string"); !ok || s != "antsbean" {
+			t.Errorf("%s: GetString got %q, ok=%v", name, s, ok)
+		}
+		if i, ok := GetInt64(c, "int"); !ok || i != 42 {
+			t.Errorf("%s: GetInt64 got %d, ok=%v", name, i, ok)
+		}
+		if _, ok := GetString(c, "int"); ok {
+			t.Errorf("%s: expect GetString of int fail", name)
+		}
+		if err := GetInto(c, "missing", &got); err != ErrCacheMiss {
+			t.Errorf("%s: expect ErrCacheMiss, got %v", name, err)
+		}
+		if err := GetInto(c, "struct", got); err == nil {
+			t.Errorf("%s: expect error for non-pointer", name)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/antsbean/wechat/cache"
 	"github.com/antsbean/wechat/util"
 )
 
@@ -43,29 +44,32 @@ func (ctx *Context) GetAccessToken() (accessToken string, err error) {
 	if ctx.accessTokenFunc != nil {
 		return ctx.accessTokenFunc(ctx)
 	}
-	accessTokenCacheKey := fmt.Sprintf("access_token_%s", ctx.AppID)
-	val := ctx.Cache.Get(accessTokenCacheKey)
-	if val != nil {
-		accessToken = val.(string)
+	accessTokenCacheKey := ctx.AccessTokenCacheKey()
+	if val, ok := cache.GetString(ctx.Cache, accessTokenCacheKey); ok {
+		accessToken = val
 		return
 	}
 
-	//从微信服务器获取
-	var resAccessToken ResAccessToken
-	resAccessToken, err = ctx.GetAccessTokenFromServer()
-	if err != nil {
+	//等待锁的过程中 context 可能已经被取消
+	if err = ctx.GoContext().Err(); err != nil {
 		return
 	}
 
-	accessToken = resAccessToken.AccessToken
-	return
+	//从微信服务器获取
+	return ctx.RefreshWithLock(accessTokenCacheKey+"_lock", func() (string, bool) {
+		val, ok := cache.GetString(ctx.Cache, accessTokenCacheKey)
+		return val, ok && val != ""
+	}, func() (string, error) {
+		resAccessToken, err := ctx.GetAccessTokenFromServer()
+		return resAccessToken.AccessToken, err
+	})
 }
 
 //GetAccessTokenFromServer 强制从微信服务器获取token
 func (ctx *Context) GetAccessTokenFromServer() (resAccessToken ResAccessToken, err error) {
 	url := fmt.Sprintf("%s?grant_type=client_credential&appid=%s&secret=%s", AccessTokenURL, ctx.AppID, ctx.AppSecret)
 	var body []byte
-	body, err = util.HTTPGet(url)
+	body, err = ctx.HTTPClient().HTTPGet(url)
 	if err != nil {
 		return
 	}
@@ -74,12 +78,84 @@ func (ctx *Context) GetAccessTokenFromServer() (resAccessToken ResAccessToken, e
 		return
 	}
 	if resAccessToken.ErrMsg != "" {
-		err = fmt.Errorf("get access_token error : errcode=%v , errormsg=%v", resAccessToken.ErrCode, resAccessToken.ErrMsg)
+		err = util.NewAPIError("GetAccessToken", resAccessToken.ErrCode, resAccessToken.ErrMsg)
 		return
 	}
 
-	accessTokenCacheKey := fmt.Sprintf("access_token_%s", ctx.AppID)
+	accessTokenCacheKey := ctx.AccessTokenCacheKey()
 	expires := resAccessToken.ExpiresIn - 1500
-	err = ctx.Cache.Set(accessTokenCacheKey, resAccessToken.AccessToken, time.Duration(expires)*time.Second)
+	//保留上一个 access_token, 用于判断失效的 access_token 是否属于当前公众号
+	if val, ok := cache.GetString(ctx.Cache, accessTokenCacheKey); ok {
+		ctx.Cache.Set(previousAccessTokenCacheKey(ctx.AppID), val, time.Duration(expires)*time.Second)
+	}
+	err = ctx.SetCredential(accessTokenCacheKey, resAccessToken.AccessToken, time.Duration(expires)*time.Second)
 	return
 }
+
+//RefreshAccessToken 在 access_token 被微信提前作废(其它服务刷新了 access_token 或重置了 AppSecret)时调用
+//stale 为已经失效的 access_token, 如果缓存中的 access_token 已经被刷新则直接返回, 否则清除缓存并从微信服务器重新获取
+func (ctx *Context) RefreshAccessToken(stale string) (accessToken string, err error) {
+	ctx.accessTokenLock.Lock()
+	defer ctx.accessTokenLock.Unlock()
+
+	if ctx.accessTokenFunc != nil {
+		err = fmt.Errorf("cannot refresh access_token obtained by custom GetAccessTokenFunc")
+		return
+	}
+
+	accessTokenCacheKey := ctx.AccessTokenCacheKey()
+	current, _ := cache.GetString(ctx.Cache, accessTokenCacheKey)
+	previous, _ := cache.GetString(ctx.Cache, previousAccessTokenCacheKey(ctx.AppID))
+	switch {
+	case stale == current:
+	case stale == previous && current != "":
+		//已经被其它请求刷新
+		accessToken = current
+		return
+	case stale == previous:
+	default:
+		err = fmt.Errorf("access_token is not issued by appid=%s", ctx.AppID)
+		return
+	}
+
+	if err = ctx.GoContext().Err(); err != nil {
+		return
+	}
+	return ctx.RefreshWithLock(accessTokenCacheKey+"_lock", func() (string, bool) {
+		val, ok := cache.GetString(ctx.Cache, accessTokenCacheKey)
+		return val, ok && val != "" && val != stale
+	}, func() (string, error) {
+		resAccessToken, err := ctx.GetAccessTokenFromServer()
+		if err != nil {
+			//刷新失败时也不再继续使用已经失效的 access_token
+			ctx.Cache.Delete(accessTokenCacheKey)
+		}
+		return resAccessToken.AccessToken, err
+	})
+}
+
+//ForceRefreshAccessToken 强制从微信服务器获取新的 access_token
+//设置了 Locker 时如果其它进程已经完成了刷新，则直接使用新的 access_token
+func (ctx *Context) ForceRefreshAccessToken() (string, error) {
+	ctx.accessTokenLock.Lock()
+	defer ctx.accessTokenLock.Unlock()
+
+	accessTokenCacheKey := ctx.AccessTokenCacheKey()
+	previous, _ := cache.GetString(ctx.Cache, accessTokenCacheKey)
+	return ctx.RefreshWithLock(accessTokenCacheKey+"_lock", func() (string, bool) {
+		val, ok := cache.GetString(ctx.Cache, accessTokenCacheKey)
+		return val, ok && val != "" && val != previous
+	}, func() (string, error) {
+		resAccessToken, err := ctx.GetAccessTokenFromServer()
+		return resAccessToken.AccessToken, err
+	})
+}
+
+//AccessTokenCacheKey 返回 access_token 在缓存中的 key
+func (ctx *Context) AccessTokenCacheKey() string {
+	return fmt.Sprintf("access_token_%s", ctx.AppID)
+}
+
+func previousAccessTokenCacheKey(appID string) string {
+	return fmt.Sprintf("access_token_%s_previous", appID)
+}
@@ -0,0 +1,80 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/util"
+)
+
+func newRetryTestContext(handler http.HandlerFunc) (*Context, func()) {
+	ts := httptest.NewServer(handler)
+	ctx := &Context{
+		AppID:           "appid",
+		AppSecret:       "secret",
+		Cache:           cache.NewMemory(),
+		Endpoints:       util.Endpoints{APIBaseURL: ts.URL},
+		accessTokenLock: new(sync.RWMutex),
+	}
+	return ctx, ts.Close
+}
+
+func TestContext_RetryOnInvalidAccessToken(t *testing.T) {
+	var tokenRequests int32
+	ctx, closeServer := newRetryTestContext(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/token":
+			atomic.AddInt32(&tokenRequests, 1)
+			fmt.Fprint(w, `{"access_token":"new_token","expires_in":7200}`)
+		default:
+			if r.URL.Query().Get("access_token") != "new_token" {
+				fmt.Fprint(w, `{"errcode":40001,"errmsg":"invalid credential"}`)
+				return
+			}
+			fmt.Fprint(w, `{"errcode":0,"errmsg":"ok"}`)
+		}
+	})
+	defer closeServer()
+	ctx.Cache.Set("access_token_appid", "old_token", time.Hour)
+
+	//第二次请求模拟并发时使用了旧 access_token 的请求，不应再次刷新
+	for i := 0; i < 2; i++ {
+		body, err := ctx.HTTPClient().HTTPGet("https://api.weixin.qq.com/cgi-bin/menu/get?access_token=old_token")
+		if err != nil || string(body) != `{"errcode":0,"errmsg":"ok"}` {
+			t.Errorf("expect retry success, body=%s, err=%v", body, err)
+		}
+	}
+
+	if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+		t.Errorf("expect access_token to be refreshed once, but refreshed %d times", n)
+	}
+	if token, _ := ctx.GetAccessToken(); token != "new_token" {
+		t.Errorf("expect cached access_token to be replaced, got %s", token)
+	}
+}
+
+func TestContext_NoRetryForForeignAccessToken(t *testing.T) {
+	var tokenRequests int32
+	ctx, closeServer := newRetryTestContext(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cgi-bin/token" {
+			atomic.AddInt32(&tokenRequests, 1)
+		}
+		fmt.Fprint(w, `{"errcode":40001,"errmsg":"invalid credential"}`)
+	})
+	defer closeServer()
+	ctx.Cache.Set("access_token_appid", "token", time.Hour)
+
+	body, err := ctx.HTTPClient().HTTPGet("https://api.weixin.qq.com/sns/userinfo?access_token=oauth_token")
+	if err != nil || string(body) != `{"errcode":40001,"errmsg":"invalid credential"}` {
+		t.Errorf("expect original response, body=%s, err=%v", body, err)
+	}
+	if n := atomic.LoadInt32(&tokenRequests); n != 0 {
+		t.Errorf("expect no refresh for access_token of other apps, but refreshed %d times", n)
+	}
+}
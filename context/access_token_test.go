@@ -1,8 +1,12 @@
 package context
 
 import (
+	gocontext "context"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
 )
 
 func TestContext_SetCustomAccessTokenFunc(t *testing.T) {
@@ -28,3 +32,22 @@ func TestContext_NoSetCustomAccessTokenFunc(t *testing.T) {
 		t.Error("error accessTokenFunc")
 	}
 }
+
+func TestContext_GetAccessTokenWithCanceledContext(t *testing.T) {
+	ctx := &Context{
+		AppID:           "appid",
+		Cache:           cache.NewMemory(),
+		accessTokenLock: new(sync.RWMutex),
+	}
+	c, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if _, err := ctx.WithContext(c).GetAccessToken(); err != gocontext.Canceled {
+		t.Errorf("expect context.Canceled but got %v", err)
+	}
+
+	ctx.Cache.Set("access_token_appid", "cached token", time.Minute)
+	res, err := ctx.WithContext(c).GetAccessToken()
+	if res != "cached token" || err != nil {
+		t.Error("expect cached token even if context is canceled")
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/antsbean/wechat/util"
+	"github.com/antsbean/wechat/cache"
 )
 
 const (
@@ -25,22 +25,49 @@ type ComponentAccessToken struct {
 
 // GetComponentAccessToken 获取 ComponentAccessToken
 func (ctx *Context) GetComponentAccessToken() (string, error) {
-	accessTokenCacheKey := fmt.Sprintf("component_access_token_%s", ctx.AppID)
-	val := ctx.Cache.Get(accessTokenCacheKey)
-	if val == nil {
+	accessTokenCacheKey := ctx.ComponentAccessTokenCacheKey()
+	val, ok := cache.GetString(ctx.Cache, accessTokenCacheKey)
+	if !ok {
 		return "", fmt.Errorf("cann't get component access token")
 	}
-	return val.(string), nil
+	return val, nil
 }
 
 // SetComponentAccessToken 通过component_verify_ticket 获取 ComponentAccessToken
+// 设置了 Locker 时多个进程同时收到 component_verify_ticket 只会刷新一次
 func (ctx *Context) SetComponentAccessToken(verifyTicket string) (*ComponentAccessToken, error) {
+	accessTokenCacheKey := ctx.ComponentAccessTokenCacheKey()
+	previous, _ := cache.GetString(ctx.Cache, accessTokenCacheKey)
+	var at *ComponentAccessToken
+	accessToken, err := ctx.RefreshWithLock(accessTokenCacheKey+"_lock", func() (string, bool) {
+		//其它进程已经完成了刷新
+		val, ok := cache.GetString(ctx.Cache, accessTokenCacheKey)
+		return val, ok && val != "" && val != previous
+	}, func() (string, error) {
+		var err error
+		at, err = ctx.refreshComponentAccessToken(verifyTicket)
+		if err != nil {
+			return "", err
+		}
+		return at.AccessToken, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if at == nil {
+		at = &ComponentAccessToken{AccessToken: accessToken}
+	}
+	return at, nil
+}
+
+// refreshComponentAccessToken 从微信服务器获取 ComponentAccessToken 并缓存
+func (ctx *Context) refreshComponentAccessToken(verifyTicket string) (*ComponentAccessToken, error) {
 	body := map[string]string{
 		"component_appid":         ctx.AppID,
 		"component_appsecret":     ctx.AppSecret,
 		"component_verify_ticket": verifyTicket,
 	}
-	respBody, err := util.PostJSON(componentAccessTokenURL, body)
+	respBody, err := ctx.HTTPClient().PostJSON(componentAccessTokenURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -50,12 +77,41 @@ func (ctx *Context) SetComponentAccessToken(verifyTicket string) (*ComponentAcce
 		return nil, err
 	}
 
-	accessTokenCacheKey := fmt.Sprintf("component_access_token_%s", ctx.AppID)
+	if at.AccessToken == "" {
+		return nil, fmt.Errorf("get component_access_token error : %s", respBody)
+	}
+
+	accessTokenCacheKey := ctx.ComponentAccessTokenCacheKey()
 	expires := at.ExpiresIn - 1500
-	ctx.Cache.Set(accessTokenCacheKey, at.AccessToken, time.Duration(expires)*time.Second)
+	ctx.SetCredential(accessTokenCacheKey, at.AccessToken, time.Duration(expires)*time.Second)
+	//component_verify_ticket 有效期为 12 小时, 保存下来用于后台刷新 component_access_token
+	ctx.Cache.Set(componentVerifyTicketCacheKey(ctx.AppID), verifyTicket, 12*time.Hour)
 	return at, nil
 }
 
+// ComponentAccessTokenCacheKey 返回 component_access_token 在缓存中的 key
+func (ctx *Context) ComponentAccessTokenCacheKey() string {
+	return fmt.Sprintf("component_access_token_%s", ctx.AppID)
+}
+
+// AuthrAccessTokenCacheKey 返回授权方 access_token 在缓存中的 key
+func AuthrAccessTokenCacheKey(appid string) string {
+	return "authorizer_access_token_" + appid
+}
+
+// GetComponentVerifyTicket 获取最近一次推送的 component_verify_ticket
+func (ctx *Context) GetComponentVerifyTicket() (string, error) {
+	val, _ := cache.GetString(ctx.Cache, componentVerifyTicketCacheKey(ctx.AppID))
+	if val == "" {
+		return "", fmt.Errorf("cann't get component verify ticket")
+	}
+	return val, nil
+}
+
+func componentVerifyTicketCacheKey(appID string) string {
+	return fmt.Sprintf("component_verify_ticket_%s", appID)
+}
+
 // GetPreCode 获取预授权码
 func (ctx *Context) GetPreCode() (string, error) {
 	cat, err := ctx.GetComponentAccessToken()
@@ -66,7 +122,7 @@ func (ctx *Context) GetPreCode() (string, error) {
 		"component_appid": ctx.AppID,
 	}
 	uri := fmt.Sprintf(getPreCodeURL, cat)
-	body, err := util.PostJSON(uri, req)
+	body, err := ctx.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return "", err
 	}
@@ -117,7 +173,7 @@ func (ctx *Context) QueryAuthCode(authCode string) (*AuthBaseInfo, error) {
 		"authorization_code": authCode,
 	}
 	uri := fmt.Sprintf(queryAuthURL, cat)
-	body, err := util.PostJSON(uri, req)
+	body, err := ctx.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -146,7 +202,7 @@ func (ctx *Context) RefreshAuthrToken(appid, refreshToken string) (*AuthrAccessT
 		"authorizer_refresh_token": refreshToken,
 	}
 	uri := fmt.Sprintf(refreshTokenURL, cat)
-	body, err := util.PostJSON(uri, req)
+	body, err := ctx.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -155,21 +211,24 @@ func (ctx *Context) RefreshAuthrToken(appid, refreshToken string) (*AuthrAccessT
 	if err := json.Unmarshal(body, ret); err != nil {
 		return nil, err
 	}
+	if ret.AccessToken == "" {
+		return nil, fmt.Errorf("refresh authorizer %s access token error : %s", appid, body)
+	}
 
-	authrTokenKey := "authorizer_access_token_" + appid
-	ctx.Cache.Set(authrTokenKey, ret.AccessToken, time.Minute*80)
+	authrTokenKey := AuthrAccessTokenCacheKey(appid)
+	ctx.SetCredential(authrTokenKey, ret.AccessToken, time.Minute*80)
 
 	return ret, nil
 }
 
 // GetAuthrAccessToken 获取授权方AccessToken
 func (ctx *Context) GetAuthrAccessToken(appid string) (string, error) {
-	authrTokenKey := "authorizer_access_token_" + appid
-	val := ctx.Cache.Get(authrTokenKey)
-	if val == nil {
+	authrTokenKey := AuthrAccessTokenCacheKey(appid)
+	val, ok := cache.GetString(ctx.Cache, authrTokenKey)
+	if !ok {
 		return "", fmt.Errorf("cannot get authorizer %s access token", appid)
 	}
-	return val.(string), nil
+	return val, nil
 }
 
 // AuthorizerInfo 授权方详细信息
@@ -204,7 +263,7 @@ func (ctx *Context) GetAuthrInfo(appid string) (*AuthorizerInfo, *AuthBaseInfo,
 	}
 
 	uri := fmt.Sprintf(getComponentInfoURL, cat)
-	body, err := util.PostJSON(uri, req)
+	body, err := ctx.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -1,10 +1,11 @@
 package context
 
 import (
-	"net/http"
+	gocontext "context"
 	"sync"
 
 	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/util"
 )
 
 // Context struct
@@ -16,11 +17,16 @@ type Context struct {
 	PayMchID       string
 	PayNotifyURL   string
 	PayKey         string
+	MessageFormat  string //消息推送的数据格式, xml 或 json, 为空时根据请求内容识别
 
-	Cache cache.Cache
+	//PreviousTokens、PreviousEncodingAESKeys 轮换之前的 Token 和 EncodingAESKey, 轮换期间校验消息推送时依次尝试
+	PreviousTokens          []string
+	PreviousEncodingAESKeys []string
+
+	//Endpoints 调用微信接口使用的域名以及发生网络错误时切换的备用域名, 为空时使用默认域名
+	Endpoints util.Endpoints
 
-	Writer  http.ResponseWriter
-	Request *http.Request
+	Cache cache.Cache
 
 	//accessTokenLock 读写锁 同一个AppID一个
 	accessTokenLock *sync.RWMutex
@@ -30,21 +36,21 @@ type Context struct {
 
 	//accessTokenFunc 自定义获取 access token 的方法
 	accessTokenFunc GetAccessTokenFunc
-}
 
-// Query returns the keyed url query value if it exists
-func (ctx *Context) Query(key string) string {
-	value, _ := ctx.GetQuery(key)
-	return value
-}
+	//httpClient 调用微信接口使用的 client
+	httpClient *util.HTTPClient
 
-// GetQuery is like Query(), it returns the keyed url query value
-func (ctx *Context) GetQuery(key string) (string, bool) {
-	req := ctx.Request
-	if values, ok := req.URL.Query()[key]; ok && len(values) > 0 {
-		return values[0], true
-	}
-	return "", false
+	//goContext 发起请求时使用的 context, 通过 WithContext 设置
+	goContext gocontext.Context
+
+	//locker 分布式锁, 用于多个进程之间协调凭证的刷新
+	locker cache.Locker
+
+	//logger 输出日志使用的 logger
+	logger util.Logger
+
+	//hooks 每次调用微信接口前后执行
+	hooks util.Hooks
 }
 
 // SetJsAPITicketLock 设置jsAPITicket的lock
@@ -56,3 +62,62 @@ func (ctx *Context) SetJsAPITicketLock(lock *sync.RWMutex) {
 func (ctx *Context) GetJsAPITicketLock() *sync.RWMutex {
 	return ctx.jsAPITicketLock
 }
+
+// SetHTTPClient 设置调用微信接口使用的 http client, 为 nil 时使用 http.DefaultClient
+func (ctx *Context) SetHTTPClient(doer util.Doer) {
+	ctx.httpClient = util.NewHTTPClient(doer)
+}
+
+// SetLogger 设置输出日志使用的 logger, 为 nil 时使用 util.DefaultLogger()
+func (ctx *Context) SetLogger(logger util.Logger) {
+	ctx.logger = logger
+}
+
+// Logger 获取输出日志使用的 logger
+func (ctx *Context) Logger() util.Logger {
+	if ctx.logger != nil {
+		return ctx.logger
+	}
+	return util.DefaultLogger()
+}
+
+// SetHooks 设置每次调用微信接口前后执行的 hooks
+func (ctx *Context) SetHooks(hooks util.Hooks) {
+	ctx.hooks = hooks
+}
+
+// HTTPClient 获取调用微信接口使用的 http client
+func (ctx *Context) HTTPClient() *util.HTTPClient {
+	client := ctx.httpClient
+	if client == nil {
+		client = util.DefaultHTTPClient()
+	}
+	client = client.WithEndpoints(ctx.Endpoints).WithHooks(ctx.hooks).WithLogger(ctx.logger)
+	if ctx.goContext != nil {
+		client = client.WithContext(ctx.goContext)
+	}
+	if ctx.accessTokenFunc == nil && ctx.accessTokenLock != nil {
+		client = client.WithTokenRefresher(ctx.RefreshAccessToken)
+	}
+	return client
+}
+
+// WithContext 返回绑定了 c 的浅拷贝，通过它发起的请求（包括获取 access_token）在 c 取消或超时后会被中止
+// 配置、缓存以及锁与原 Context 共享
+func (ctx *Context) WithContext(c gocontext.Context) *Context {
+	if c == nil {
+		panic("nil context")
+	}
+	ctx2 := new(Context)
+	*ctx2 = *ctx
+	ctx2.goContext = c
+	return ctx2
+}
+
+// GoContext 返回通过 WithContext 设置的 context，未设置时为 context.Background()
+func (ctx *Context) GoContext() gocontext.Context {
+	if ctx.goContext != nil {
+		return ctx.goContext
+	}
+	return gocontext.Background()
+}
@@ -0,0 +1,34 @@
+package context
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
+)
+
+//SetCredential 缓存 access_token、ticket 等凭证，同时记录缓存的过期时间，供后台刷新时判断是否需要刷新
+func (ctx *Context) SetCredential(key, val string, timeout time.Duration) error {
+	if err := ctx.Cache.Set(key, val, timeout); err != nil {
+		return err
+	}
+	expiresAt := strconv.FormatInt(time.Now().Add(timeout).Unix(), 10)
+	return ctx.Cache.Set(credentialExpiresAtKey(key), expiresAt, timeout)
+}
+
+//CredentialExpiresAt 返回通过 SetCredential 缓存的凭证的过期时间，凭证不存在时 ok 为 false
+func (ctx *Context) CredentialExpiresAt(key string) (expiresAt time.Time, ok bool) {
+	if !ctx.Cache.IsExist(key) {
+		return
+	}
+	val, _ := cache.GetString(ctx.Cache, credentialExpiresAtKey(key))
+	ts, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return
+	}
+	return time.Unix(ts, 0), true
+}
+
+func credentialExpiresAtKey(key string) string {
+	return key + "_expires_at"
+}
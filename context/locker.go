@@ -0,0 +1,60 @@
+package context
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
+)
+
+var (
+	//refreshLockTTL 刷新凭证时持有分布式锁的最长时间
+	refreshLockTTL = 10 * time.Second
+	//refreshWaitTimeout 等待其它进程刷新凭证的最长时间
+	refreshWaitTimeout = 10 * time.Second
+	//refreshPollInterval 等待其它进程刷新凭证时检查缓存的间隔
+	refreshPollInterval = 100 * time.Millisecond
+)
+
+//SetLocker 设置分布式锁，多个进程共享缓存时保证只有一个进程刷新 access_token、jsapi_ticket 等凭证
+func (ctx *Context) SetLocker(locker cache.Locker) {
+	ctx.locker = locker
+}
+
+//RefreshWithLock 在分布式锁的保护下执行 refresh
+//cached 用于判断缓存中是否已经有可用的值：获取到锁之后如果已经有可用的值则不再刷新；
+//锁被其它进程持有时则等待，直到 cached 返回可用的值
+//未设置 Locker 时直接执行 refresh
+func (ctx *Context) RefreshWithLock(lockKey string, cached func() (string, bool), refresh func() (string, error)) (string, error) {
+	if ctx.locker == nil {
+		return refresh()
+	}
+
+	deadline := time.Now().Add(refreshWaitTimeout)
+	for {
+		unlock, ok, err := ctx.locker.TryLock(lockKey, refreshLockTTL)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			defer unlock()
+			//获取锁之前其它进程可能刚刚完成刷新
+			if val, ok := cached(); ok {
+				return val, nil
+			}
+			return refresh()
+		}
+
+		if val, ok := cached(); ok {
+			return val, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("wait for %s timeout", lockKey)
+		}
+		select {
+		case <-ctx.GoContext().Done():
+			return "", ctx.GoContext().Err()
+		case <-time.After(refreshPollInterval):
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
+)
+
+func TestContext_RefreshWithLock(t *testing.T) {
+	var tokenRequests int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenRequests, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, `{"access_token":"token","expires_in":7200}`)
+	}
+
+	//Memory 并发安全，模拟多个进程共享的 redis
+	shared := cache.NewMemory()
+	locker := cache.NewMemory()
+
+	//每个 Context 有自己的进程内锁，模拟多个进程
+	var contexts []*Context
+	for i := 0; i < 5; i++ {
+		ctx, closeServer := newRetryTestContext(handler)
+		defer closeServer()
+		ctx.Cache = shared
+		ctx.SetLocker(locker)
+		contexts = append(contexts, ctx)
+	}
+
+	var wg sync.WaitGroup
+	for _, ctx := range contexts {
+		wg.Add(1)
+		go func(ctx *Context) {
+			defer wg.Done()
+			token, err := ctx.GetAccessToken()
+			if token != "token" || err != nil {
+				t.Errorf("expect token but got %s, err=%v", token, err)
+			}
+		}(ctx)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+		t.Errorf("expect access_token to be fetched once, but fetched %d times", n)
+	}
+}
@@ -3,10 +3,10 @@ package context
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
+	"github.com/antsbean/wechat/cache"
 	"github.com/antsbean/wechat/util"
 )
 
@@ -34,9 +34,13 @@ func (ctx *Context) GetQyAccessToken() (accessToken string, err error) {
 	defer ctx.accessTokenLock.Unlock()
 
 	accessTokenCacheKey := fmt.Sprintf("qy_access_token_%s", ctx.AppID)
-	val := ctx.Cache.Get(accessTokenCacheKey)
-	if val != nil {
-		accessToken = val.(string)
+	if val, ok := cache.GetString(ctx.Cache, accessTokenCacheKey); ok {
+		accessToken = val
+		return
+	}
+
+	//等待锁的过程中 context 可能已经被取消
+	if err = ctx.GoContext().Err(); err != nil {
 		return
 	}
 
@@ -53,10 +57,10 @@ func (ctx *Context) GetQyAccessToken() (accessToken string, err error) {
 
 //GetQyAccessTokenFromServer 强制从微信服务器获取token
 func (ctx *Context) GetQyAccessTokenFromServer() (resQyAccessToken ResQyAccessToken, err error) {
-	log.Printf("GetQyAccessTokenFromServer")
+	ctx.Logger().Debugf("GetQyAccessTokenFromServer")
 	url := fmt.Sprintf(qyAccessTokenURL, ctx.AppID, ctx.AppSecret)
 	var body []byte
-	body, err = util.HTTPGet(url)
+	body, err = ctx.HTTPClient().HTTPGet(url)
 	if err != nil {
 		return
 	}
@@ -65,7 +69,7 @@ func (ctx *Context) GetQyAccessTokenFromServer() (resQyAccessToken ResQyAccessTo
 		return
 	}
 	if resQyAccessToken.ErrCode != 0 {
-		err = fmt.Errorf("get qy_access_token error : errcode=%v , errormsg=%v", resQyAccessToken.ErrCode, resQyAccessToken.ErrMsg)
+		err = util.NewAPIError("GetQyAccessToken", resQyAccessToken.ErrCode, resQyAccessToken.ErrMsg)
 		return
 	}
 
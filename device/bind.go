@@ -25,7 +25,7 @@ func (d *Device) Bind(req ReqBind) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", uriBind, accessToken)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.HTTPClient().PostJSON(uri, req); err != nil {
 		return
 	}
 	var result resBind
@@ -33,7 +33,7 @@ func (d *Device) Bind(req ReqBind) (err error) {
 		return
 	}
 	if result.BaseResp.ErrCode != 0 {
-		err = fmt.Errorf("DeviceBind Error , errcode=%d , errmsg=%s", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
+		err = util.NewAPIError("DeviceBind", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
 		return
 	}
 	return
@@ -47,7 +47,7 @@ func (d *Device) Unbind(req ReqBind) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", uriUnbind, accessToken)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.HTTPClient().PostJSON(uri, req); err != nil {
 		return
 	}
 	var result resBind
@@ -55,7 +55,7 @@ func (d *Device) Unbind(req ReqBind) (err error) {
 		return
 	}
 	if result.BaseResp.ErrCode != 0 {
-		err = fmt.Errorf("DeviceBind Error , errcode=%d , errmsg=%s", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
+		err = util.NewAPIError("DeviceUnbind", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
 		return
 	}
 	return
@@ -69,7 +69,7 @@ func (d *Device) CompelBind(req ReqBind) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", uriCompelBind, accessToken)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.HTTPClient().PostJSON(uri, req); err != nil {
 		return
 	}
 	var result resBind
@@ -77,7 +77,7 @@ func (d *Device) CompelBind(req ReqBind) (err error) {
 		return
 	}
 	if result.BaseResp.ErrCode != 0 {
-		err = fmt.Errorf("DeviceBind Error , errcode=%d , errmsg=%s", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
+		err = util.NewAPIError("DeviceCompelBind", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
 		return
 	}
 	return
@@ -91,7 +91,7 @@ func (d *Device) CompelUnbind(req ReqBind) (err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", uriCompelUnbind, accessToken)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.HTTPClient().PostJSON(uri, req); err != nil {
 		return
 	}
 	var result resBind
@@ -99,7 +99,7 @@ func (d *Device) CompelUnbind(req ReqBind) (err error) {
 		return
 	}
 	if result.BaseResp.ErrCode != 0 {
-		err = fmt.Errorf("DeviceBind Error , errcode=%d , errmsg=%s", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
+		err = util.NewAPIError("DeviceCompelUnbind", result.BaseResp.ErrCode, result.BaseResp.ErrMsg)
 		return
 	}
 	return
@@ -1,6 +1,7 @@
 package device
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -31,6 +32,11 @@ func NewDevice(context *context.Context) *Device {
 	return device
 }
 
+//WithContext 返回使用 ctx 发起请求的 Device，ctx 取消或超时后请求会被中止
+func (d *Device) WithContext(ctx gocontext.Context) *Device {
+	return NewDevice(d.Context.WithContext(ctx))
+}
+
 // ResDeviceState 设备状态响应实体
 type ResDeviceState struct {
 	util.CommonError
@@ -46,14 +52,14 @@ func (d *Device) State(device string) (res ResDeviceState, err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s&device_id=%s", uriState, accessToken, device)
 	var response []byte
-	if response, err = util.HTTPGet(uri); err != nil {
+	if response, err = d.HTTPClient().HTTPGet(uri); err != nil {
 		return
 	}
 	if err = json.Unmarshal(response, &res); err != nil {
 		return
 	}
 	if res.ErrCode != 0 {
-		err = fmt.Errorf("DeviceState Error , errcode=%d , errmsg=%s", res.ErrCode, res.ErrMsg)
+		err = util.NewAPIError("DeviceState", res.ErrCode, res.ErrMsg)
 		return
 	}
 	return
@@ -29,14 +29,14 @@ func (d *Device) CreateQRCode(devices []string) (res ResCreateQRCode, err error)
 		"device_id_list": devices,
 	}
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.HTTPClient().PostJSON(uri, req); err != nil {
 		return
 	}
 	if err = json.Unmarshal(response, &res); err != nil {
 		return
 	}
 	if res.ErrCode != 0 {
-		err = fmt.Errorf("DeviceCreateQRCode Error , errcode=%d , errmsg=%s", res.ErrCode, res.ErrMsg)
+		err = util.NewAPIError("DeviceCreateQRCode", res.ErrCode, res.ErrMsg)
 		return
 	}
 	return
@@ -60,16 +60,16 @@ func (d *Device) VerifyQRCode(ticket string) (res ResVerifyQRCode, err error) {
 	req := map[string]interface{}{
 		"ticket": ticket,
 	}
-	fmt.Println(req)
+	d.Logger().Debugf("VerifyQRCode request = %v", req)
 	var response []byte
-	if response, err = util.PostJSON(uri, req); err != nil {
+	if response, err = d.HTTPClient().PostJSON(uri, req); err != nil {
 		return
 	}
 	if err = json.Unmarshal(response, &res); err != nil {
 		return
 	}
 	if res.ErrCode != 0 {
-		err = fmt.Errorf("DeviceCreateQRCode Error , errcode=%d , errmsg=%s", res.ErrCode, res.ErrMsg)
+		err = util.NewAPIError("DeviceVerifyQRCode", res.ErrCode, res.ErrMsg)
 		return
 	}
 	return
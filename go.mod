@@ -3,11 +3,13 @@ module github.com/antsbean/wechat
 go 1.13
 
 require (
+	github.com/alicebob/miniredis/v2 v2.18.0
 	github.com/astaxie/beego v1.7.1
 	github.com/bradfitz/gomemcache v0.0.0-20160117192205-fb1f79c6b65a
 	github.com/fatih/structs v1.1.0
 	github.com/gin-gonic/gin v1.1.4
-	github.com/golang/protobuf v0.0.0-20161117033126-8ee79997227b // indirect
+	github.com/go-redis/redis/v7 v7.4.0
+	github.com/golang/protobuf v1.3.2 // indirect
 	github.com/gomodule/redigo v2.0.1-0.20180627144507-2cd21d9966bf+incompatible
 	github.com/kr/pretty v0.1.0
 	github.com/manucorporat/sse v0.0.0-20160126180136-ee05b128a739 // indirect
@@ -1,15 +1,27 @@
 package js
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/antsbean/wechat/cache"
 	"github.com/antsbean/wechat/context"
 	"github.com/antsbean/wechat/util"
 )
 
-const getTicketURL = "https://api.weixin.qq.com/cgi-bin/ticket/getticket?access_token=%s&type=jsapi"
+const getTicketURL = "https://api.weixin.qq.com/cgi-bin/ticket/getticket?access_token=%s&type=%s"
+
+// TicketType ticket 的类型
+type TicketType string
+
+const (
+	// TicketTypeJsAPI jsapi_ticket, 用于 js-sdk 签名
+	TicketTypeJsAPI TicketType = "jsapi"
+	// TicketTypeWxCard 卡券 api_ticket, 用于卡券签名
+	TicketTypeWxCard TicketType = "wx_card"
+)
 
 // Js struct
 type Js struct {
@@ -39,6 +51,11 @@ func NewJs(context *context.Context) *Js {
 	return js
 }
 
+//WithContext 返回使用 ctx 发起请求的 Js，ctx 取消或超时后请求会被中止
+func (js *Js) WithContext(ctx gocontext.Context) *Js {
+	return NewJs(js.Context.WithContext(ctx))
+}
+
 //GetConfig 获取jssdk需要的配置参数
 //uri 为当前网页地址
 func (js *Js) GetConfig(uri string) (config *Config, err error) {
@@ -63,27 +80,63 @@ func (js *Js) GetConfig(uri string) (config *Config, err error) {
 
 //GetTicket 获取jsapi_ticket
 func (js *Js) GetTicket() (ticketStr string, err error) {
+	return js.getTicket(TicketTypeJsAPI)
+}
+
+//GetCardTicket 获取卡券 api_ticket
+func (js *Js) GetCardTicket() (ticketStr string, err error) {
+	return js.getTicket(TicketTypeWxCard)
+}
+
+//RefreshTicket 强制从服务器中获取 ticket 并更新缓存
+//设置了 Locker 时，其它进程已经刷新过的情况下直接使用新的 ticket
+func (js *Js) RefreshTicket(ticketType TicketType) (ticketStr string, err error) {
+	js.GetJsAPITicketLock().Lock()
+	defer js.GetJsAPITicketLock().Unlock()
+
+	ticketCacheKey := js.TicketCacheKey(ticketType)
+	previous, _ := cache.GetString(js.Cache, ticketCacheKey)
+	return js.RefreshWithLock(ticketCacheKey+"_lock", func() (string, bool) {
+		val, ok := cache.GetString(js.Cache, ticketCacheKey)
+		return val, ok && val != "" && val != previous
+	}, func() (string, error) {
+		ticket, err := js.getTicketFromServer(ticketType)
+		return ticket.Ticket, err
+	})
+}
+
+//TicketCacheKey 返回 ticket 在缓存中的 key
+func (js *Js) TicketCacheKey(ticketType TicketType) string {
+	if ticketType == TicketTypeJsAPI {
+		return fmt.Sprintf("jsapi_ticket_%s", js.AppID)
+	}
+	return fmt.Sprintf("%s_ticket_%s", ticketType, js.AppID)
+}
+
+func (js *Js) getTicket(ticketType TicketType) (ticketStr string, err error) {
 	js.GetJsAPITicketLock().Lock()
 	defer js.GetJsAPITicketLock().Unlock()
 
 	//先从cache中取
-	jsAPITicketCacheKey := fmt.Sprintf("jsapi_ticket_%s", js.AppID)
-	val := js.Cache.Get(jsAPITicketCacheKey)
-	if val != nil {
-		ticketStr = val.(string)
+	ticketCacheKey := js.TicketCacheKey(ticketType)
+	if val, ok := cache.GetString(js.Cache, ticketCacheKey); ok {
+		ticketStr = val
 		return
 	}
-	var ticket resTicket
-	ticket, err = js.getTicketFromServer()
-	if err != nil {
+	if err = js.GoContext().Err(); err != nil {
 		return
 	}
-	ticketStr = ticket.Ticket
-	return
+	return js.RefreshWithLock(ticketCacheKey+"_lock", func() (string, bool) {
+		val, ok := cache.GetString(js.Cache, ticketCacheKey)
+		return val, ok && val != ""
+	}, func() (string, error) {
+		ticket, err := js.getTicketFromServer(ticketType)
+		return ticket.Ticket, err
+	})
 }
 
 //getTicketFromServer 强制从服务器中获取ticket
-func (js *Js) getTicketFromServer() (ticket resTicket, err error) {
+func (js *Js) getTicketFromServer(ticketType TicketType) (ticket resTicket, err error) {
 	var accessToken string
 	accessToken, err = js.GetAccessToken()
 	if err != nil {
@@ -91,19 +144,21 @@ func (js *Js) getTicketFromServer() (ticket resTicket, err error) {
 	}
 
 	var response []byte
-	url := fmt.Sprintf(getTicketURL, accessToken)
-	response, err = util.HTTPGet(url)
+	url := fmt.Sprintf(getTicketURL, accessToken, ticketType)
+	response, err = js.HTTPClient().HTTPGet(url)
+	if err != nil {
+		return
+	}
 	err = json.Unmarshal(response, &ticket)
 	if err != nil {
 		return
 	}
 	if ticket.ErrCode != 0 {
-		err = fmt.Errorf("getTicket Error : errcode=%d , errmsg=%s", ticket.ErrCode, ticket.ErrMsg)
+		err = util.NewAPIError("GetTicket", ticket.ErrCode, ticket.ErrMsg)
 		return
 	}
 
-	jsAPITicketCacheKey := fmt.Sprintf("jsapi_ticket_%s", js.AppID)
 	expires := ticket.ExpiresIn - 1500
-	err = js.Cache.Set(jsAPITicketCacheKey, ticket.Ticket, time.Duration(expires)*time.Second)
+	err = js.SetCredential(js.TicketCacheKey(ticketType), ticket.Ticket, time.Duration(expires)*time.Second)
 	return
 }
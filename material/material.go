@@ -1,6 +1,7 @@
 package material
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,6 +29,11 @@ func NewMaterial(context *context.Context) *Material {
 	return material
 }
 
+//WithContext 返回使用 ctx 发起请求的 Material，ctx 取消或超时后请求会被中止
+func (material *Material) WithContext(ctx gocontext.Context) *Material {
+	return NewMaterial(material.Context.WithContext(ctx))
+}
+
 //Article 永久图文素材
 type Article struct {
 	Title            string `json:"title"`
@@ -54,7 +60,7 @@ func (material *Material) GetNews(id string) ([]*Article, error) {
 		MediaID string `json:"media_id"`
 	}
 	req.MediaID = id
-	responseBytes, err := util.PostJSON(uri, req)
+	responseBytes, err := material.HTTPClient().PostJSON(uri, req)
 
 	var res struct {
 		NewsItem []*Article `json:"news_item"`
@@ -90,7 +96,7 @@ func (material *Material) AddNews(articles []*Article) (mediaID string, err erro
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", addNewsURL, accessToken)
-	responseBytes, err := util.PostJSON(uri, req)
+	responseBytes, err := material.HTTPClient().PostJSON(uri, req)
 	var res resArticles
 	err = json.Unmarshal(responseBytes, &res)
 	if err != nil {
@@ -121,7 +127,7 @@ func (material *Material) AddMaterial(mediaType MediaType, filename string) (med
 
 	uri := fmt.Sprintf("%s?access_token=%s&type=%s", addMaterialURL, accessToken, mediaType)
 	var response []byte
-	response, err = util.PostFile("media", filename, uri)
+	response, err = material.HTTPClient().PostFile("media", filename, uri)
 	if err != nil {
 		return
 	}
@@ -131,7 +137,7 @@ func (material *Material) AddMaterial(mediaType MediaType, filename string) (med
 		return
 	}
 	if resMaterial.ErrCode != 0 {
-		err = fmt.Errorf("AddMaterial error : errcode=%v , errmsg=%v", resMaterial.ErrCode, resMaterial.ErrMsg)
+		err = util.NewAPIError("AddMaterial", resMaterial.ErrCode, resMaterial.ErrMsg)
 		return
 	}
 	mediaID = resMaterial.MediaID
@@ -178,7 +184,7 @@ func (material *Material) AddVideo(filename, title, introduction string) (mediaI
 	}
 
 	var response []byte
-	response, err = util.PostMultipartForm(fields, uri)
+	response, err = material.HTTPClient().PostMultipartForm(fields, uri)
 	if err != nil {
 		return
 	}
@@ -189,7 +195,7 @@ func (material *Material) AddVideo(filename, title, introduction string) (mediaI
 		return
 	}
 	if resMaterial.ErrCode != 0 {
-		err = fmt.Errorf("AddMaterial error : errcode=%v , errmsg=%v", resMaterial.ErrCode, resMaterial.ErrMsg)
+		err = util.NewAPIError("AddMaterial", resMaterial.ErrCode, resMaterial.ErrMsg)
 		return
 	}
 	mediaID = resMaterial.MediaID
@@ -209,7 +215,7 @@ func (material *Material) DeleteMaterial(mediaID string) error {
 	}
 
 	uri := fmt.Sprintf("%s?access_token=%s", delMaterialURL, accessToken)
-	response, err := util.PostJSON(uri, reqDeleteMaterial{mediaID})
+	response, err := material.HTTPClient().PostJSON(uri, reqDeleteMaterial{mediaID})
 	if err != nil {
 		return err
 	}
@@ -50,7 +50,7 @@ func (material *Material) MediaUploadBytesWithAK(ak string, mediaType MediaType,
 		},
 	}
 	var response []byte
-	response, err = util.PostMultipartFormWithBytes(fields, uri)
+	response, err = material.HTTPClient().PostMultipartFormWithBytes(fields, uri)
 	if err != nil {
 		return
 	}
@@ -59,7 +59,7 @@ func (material *Material) MediaUploadBytesWithAK(ak string, mediaType MediaType,
 		return
 	}
 	if media.ErrCode != 0 {
-		err = fmt.Errorf("MediaUpload error : errcode=%v , errmsg=%v", media.ErrCode, media.ErrMsg)
+		err = util.NewAPIError("MediaUpload", media.ErrCode, media.ErrMsg)
 		return
 	}
 	return
@@ -69,7 +69,7 @@ func (material *Material) MediaUploadBytesWithAK(ak string, mediaType MediaType,
 func (material *Material) MediaUploadWithAK(ak string, mediaType MediaType, filename string) (media Media, err error) {
 	uri := fmt.Sprintf("%s?access_token=%s&type=%s", mediaUploadURL, ak, mediaType)
 	var response []byte
-	response, err = util.PostFile("media", filename, uri)
+	response, err = material.HTTPClient().PostFile("media", filename, uri)
 	if err != nil {
 		return
 	}
@@ -78,7 +78,7 @@ func (material *Material) MediaUploadWithAK(ak string, mediaType MediaType, file
 		return
 	}
 	if media.ErrCode != 0 {
-		err = fmt.Errorf("MediaUpload error : errcode=%v , errmsg=%v", media.ErrCode, media.ErrMsg)
+		err = util.NewAPIError("MediaUpload", media.ErrCode, media.ErrMsg)
 		return
 	}
 	return
@@ -129,7 +129,7 @@ func (material *Material) ImageUpload(filename string) (url string, err error) {
 
 	uri := fmt.Sprintf("%s?access_token=%s", mediaUploadImageURL, accessToken)
 	var response []byte
-	response, err = util.PostFile("media", filename, uri)
+	response, err = material.HTTPClient().PostFile("media", filename, uri)
 	if err != nil {
 		return
 	}
@@ -139,7 +139,7 @@ func (material *Material) ImageUpload(filename string) (url string, err error) {
 		return
 	}
 	if image.ErrCode != 0 {
-		err = fmt.Errorf("UploadImage error : errcode=%v , errmsg=%v", image.ErrCode, image.ErrMsg)
+		err = util.NewAPIError("UploadImage", image.ErrCode, image.ErrMsg)
 		return
 	}
 	url = image.URL
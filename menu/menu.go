@@ -1,6 +1,7 @@
 package menu
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -118,6 +119,11 @@ func NewMenu(context *context.Context) *Menu {
 	return menu
 }
 
+//WithContext 返回使用 ctx 发起请求的 Menu，ctx 取消或超时后请求会被中止
+func (menu *Menu) WithContext(ctx gocontext.Context) *Menu {
+	return NewMenu(menu.Context.WithContext(ctx))
+}
+
 //SetMenu 设置按钮
 func (menu *Menu) SetMenu(buttons []*Button) error {
 	accessToken, err := menu.GetAccessToken()
@@ -130,7 +136,7 @@ func (menu *Menu) SetMenu(buttons []*Button) error {
 		Button: buttons,
 	}
 
-	response, err := util.PostJSON(uri, reqMenu)
+	response, err := menu.HTTPClient().PostJSON(uri, reqMenu)
 	if err != nil {
 		return err
 	}
@@ -147,7 +153,7 @@ func (menu *Menu) GetMenu() (resMenu ResMenu, err error) {
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", menuGetURL, accessToken)
 	var response []byte
-	response, err = util.HTTPGet(uri)
+	response, err = menu.HTTPClient().HTTPGet(uri)
 	if err != nil {
 		return
 	}
@@ -156,7 +162,7 @@ func (menu *Menu) GetMenu() (resMenu ResMenu, err error) {
 		return
 	}
 	if resMenu.ErrCode != 0 {
-		err = fmt.Errorf("GetMenu Error , errcode=%d , errmsg=%s", resMenu.ErrCode, resMenu.ErrMsg)
+		err = util.NewAPIError("GetMenu", resMenu.ErrCode, resMenu.ErrMsg)
 		return
 	}
 	return
@@ -169,7 +175,7 @@ func (menu *Menu) DeleteMenu() error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", menuDeleteURL, accessToken)
-	response, err := util.HTTPGet(uri)
+	response, err := menu.HTTPClient().HTTPGet(uri)
 	if err != nil {
 		return err
 	}
@@ -190,7 +196,7 @@ func (menu *Menu) AddConditional(buttons []*Button, matchRule *MatchRule) error
 		MatchRule: matchRule,
 	}
 
-	response, err := util.PostJSON(uri, reqMenu)
+	response, err := menu.HTTPClient().PostJSON(uri, reqMenu)
 	if err != nil {
 		return err
 	}
@@ -210,7 +216,7 @@ func (menu *Menu) DeleteConditional(menuID int64) error {
 		MenuID: menuID,
 	}
 
-	response, err := util.PostJSON(uri, reqDeleteConditional)
+	response, err := menu.HTTPClient().PostJSON(uri, reqDeleteConditional)
 	if err != nil {
 		return err
 	}
@@ -228,7 +234,7 @@ func (menu *Menu) MenuTryMatch(userID string) (buttons []Button, err error) {
 	uri := fmt.Sprintf("%s?access_token=%s", menuTryMatchURL, accessToken)
 	reqMenuTryMatch := &reqMenuTryMatch{userID}
 	var response []byte
-	response, err = util.PostJSON(uri, reqMenuTryMatch)
+	response, err = menu.HTTPClient().PostJSON(uri, reqMenuTryMatch)
 	if err != nil {
 		return
 	}
@@ -238,7 +244,7 @@ func (menu *Menu) MenuTryMatch(userID string) (buttons []Button, err error) {
 		return
 	}
 	if resMenuTryMatch.ErrCode != 0 {
-		err = fmt.Errorf("MenuTryMatch Error , errcode=%d , errmsg=%s", resMenuTryMatch.ErrCode, resMenuTryMatch.ErrMsg)
+		err = util.NewAPIError("MenuTryMatch", resMenuTryMatch.ErrCode, resMenuTryMatch.ErrMsg)
 		return
 	}
 	buttons = resMenuTryMatch.Button
@@ -254,7 +260,7 @@ func (menu *Menu) GetCurrentSelfMenuInfo() (resSelfMenuInfo ResSelfMenuInfo, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", menuSelfMenuInfoURL, accessToken)
 	var response []byte
-	response, err = util.HTTPGet(uri)
+	response, err = menu.HTTPClient().HTTPGet(uri)
 	if err != nil {
 		return
 	}
@@ -263,7 +269,7 @@ func (menu *Menu) GetCurrentSelfMenuInfo() (resSelfMenuInfo ResSelfMenuInfo, err
 		return
 	}
 	if resSelfMenuInfo.ErrCode != 0 {
-		err = fmt.Errorf("GetCurrentSelfMenuInfo Error , errcode=%d , errmsg=%s", resSelfMenuInfo.ErrCode, resSelfMenuInfo.ErrMsg)
+		err = util.NewAPIError("GetCurrentSelfMenuInfo", resSelfMenuInfo.ErrCode, resSelfMenuInfo.ErrMsg)
 		return
 	}
 	return
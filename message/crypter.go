@@ -0,0 +1,75 @@
+package message
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/antsbean/wechat/util"
+)
+
+//ErrInvalidMsgSignature 加密消息的签名校验失败
+var ErrInvalidMsgSignature = errors.New("消息不合法，验证签名失败")
+
+//Crypter 消息加解密, 不依赖 server.Server, 可以用于测试或者转发消息
+type Crypter struct {
+	AppID          string
+	Token          string
+	EncodingAESKey string
+}
+
+//NewCrypter init
+func NewCrypter(appID, token, encodingAESKey string) *Crypter {
+	return &Crypter{
+		AppID:          appID,
+		Token:          token,
+		EncodingAESKey: encodingAESKey,
+	}
+}
+
+//Encrypt 加密明文消息, 使用新生成的 timestamp 和 nonce 签名
+func (c *Crypter) Encrypt(rawMsg []byte) (*ResponseEncryptedXMLMsg, error) {
+	return c.EncryptWith(rawMsg, util.GetCurrTs(), util.RandomStr(16))
+}
+
+//EncryptWith 使用指定的 timestamp 和 nonce 加密明文消息并签名, timestamp 为 0 或者 nonce 为空时重新生成
+func (c *Crypter) EncryptWith(rawMsg []byte, timestamp int64, nonce string) (*ResponseEncryptedXMLMsg, error) {
+	if timestamp == 0 {
+		timestamp = util.GetCurrTs()
+	}
+	if nonce == "" {
+		nonce = util.RandomStr(16)
+	}
+	return c.encrypt([]byte(util.RandomStr(16)), rawMsg, timestamp, nonce)
+}
+
+func (c *Crypter) encrypt(random, rawMsg []byte, timestamp int64, nonce string) (*ResponseEncryptedXMLMsg, error) {
+	encryptedMsg, err := util.EncryptMsg(random, rawMsg, c.AppID, c.EncodingAESKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseEncryptedXMLMsg{
+		EncryptedMsg: string(encryptedMsg),
+		MsgSignature: c.Signature(strconv.FormatInt(timestamp, 10), nonce, string(encryptedMsg)),
+		Timestamp:    timestamp,
+		Nonce:        nonce,
+	}, nil
+}
+
+//Decrypt 校验 msg_signature 并解密消息, 参数对应消息推送 url 中的 msg_signature、timestamp、nonce 以及消息体中的 Encrypt
+func (c *Crypter) Decrypt(msgSignature, timestamp, nonce, encryptedMsg string) ([]byte, error) {
+	if msgSignature != c.Signature(timestamp, nonce, encryptedMsg) {
+		return nil, ErrInvalidMsgSignature
+	}
+	_, rawMsg, err := util.DecryptMsg(c.AppID, encryptedMsg, c.EncodingAESKey)
+	return rawMsg, err
+}
+
+//DecryptResponse 校验并解密 Encrypt 生成的回复
+func (c *Crypter) DecryptResponse(msg *ResponseEncryptedXMLMsg) ([]byte, error) {
+	return c.Decrypt(msg.MsgSignature, strconv.FormatInt(msg.Timestamp, 10), msg.Nonce, msg.EncryptedMsg)
+}
+
+//Signature 计算加密消息的签名
+func (c *Crypter) Signature(timestamp, nonce, encryptedMsg string) string {
+	return util.Signature(c.Token, timestamp, nonce, encryptedMsg)
+}
@@ -0,0 +1,45 @@
+package message
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestCrypter(t *testing.T) {
+	crypter := NewCrypter("appid", "token", "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG")
+	raw := []byte(`<xml><Content><![CDATA[hi]]></Content></xml>`)
+
+	msg, err := crypter.Encrypt(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Timestamp == 0 || msg.Nonce == "" || msg.MsgSignature == "" {
+		t.Errorf("expect timestamp, nonce and signature generated, got %+v", msg)
+	}
+	decrypted, err := crypter.DecryptResponse(msg)
+	if err != nil || string(decrypted) != string(raw) {
+		t.Errorf("unexpected decrypted message %s, err=%v", decrypted, err)
+	}
+
+	//信封可以序列化为 xml 之后再解析
+	data, _ := xml.Marshal(msg)
+	var envelope ResponseEncryptedXMLMsg
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crypter.DecryptResponse(&envelope); err != nil {
+		t.Errorf("decrypt envelope error: %v", err)
+	}
+
+	msg, _ = crypter.EncryptWith(raw, 1500000000, "nonce")
+	if msg.Timestamp != 1500000000 || msg.Nonce != "nonce" {
+		t.Errorf("expect given timestamp and nonce, got %+v", msg)
+	}
+	msg.Nonce = "other"
+	if _, err := crypter.DecryptResponse(msg); err != ErrInvalidMsgSignature {
+		t.Errorf("expect ErrInvalidMsgSignature, got %v", err)
+	}
+	if _, err := NewCrypter("appid", "other", crypter.EncodingAESKey).Decrypt(msg.MsgSignature, "1500000000", "nonce", msg.EncryptedMsg); err != ErrInvalidMsgSignature {
+		t.Errorf("expect ErrInvalidMsgSignature for wrong token, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package message
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 	"github.com/antsbean/wechat/context"
@@ -23,6 +24,11 @@ func NewMessageManager(context *context.Context) *Manager {
 	}
 }
 
+//WithContext 返回使用 ctx 发起请求的 Manager，ctx 取消或超时后请求会被中止
+func (manager *Manager) WithContext(ctx gocontext.Context) *Manager {
+	return NewMessageManager(manager.Context.WithContext(ctx))
+}
+
 //CustomerMessage  客服消息
 type CustomerMessage struct {
 	ToUser          string                `json:"touser"`                    //接受者OpenID
@@ -72,6 +78,49 @@ func NewCustomerVoiceMessage(toUser, mediaID string) *CustomerMessage {
 	}
 }
 
+//NewCustomerMessageFromReply 将被动回复的消息转换为客服消息, 用于在被动回复超时之后通过客服消息接口回复用户
+func NewCustomerMessageFromReply(toUser string, reply *Reply) (*CustomerMessage, error) {
+	msg := &CustomerMessage{
+		ToUser:  toUser,
+		Msgtype: reply.MsgType,
+	}
+	switch data := reply.MsgData.(type) {
+	case *Text:
+		msg.Text = &MediaText{string(data.Content)}
+	case *Image:
+		msg.Image = &MediaResource{data.Image.MediaID}
+	case *Voice:
+		msg.Voice = &MediaResource{data.Voice.MediaID}
+	case *Video:
+		msg.Video = &MediaVideo{
+			MediaID:     data.Video.MediaID,
+			Title:       data.Video.Title,
+			Description: data.Video.Description,
+		}
+	case *Music:
+		msg.Music = &MediaMusic{
+			Title:        data.Music.Title,
+			Description:  data.Music.Description,
+			Musicurl:     data.Music.MusicURL,
+			Hqmusicurl:   data.Music.HQMusicURL,
+			ThumbMediaID: data.Music.ThumbMediaID,
+		}
+	case *News:
+		msg.News = &MediaNews{}
+		for _, article := range data.Articles {
+			msg.News.Articles = append(msg.News.Articles, MediaArticles{
+				Title:       article.Title,
+				Description: article.Description,
+				URL:         article.URL,
+				Picurl:      article.PicURL,
+			})
+		}
+	default:
+		return nil, ErrUnsupportReply
+	}
+	return msg, nil
+}
+
 //MediaText 文本消息的文字
 type MediaText struct {
 	Content string `json:"content"`
@@ -145,14 +194,17 @@ func (manager *Manager) Send(msg *CustomerMessage) error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", customerSendMessage, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := manager.HTTPClient().PostJSON(uri, msg)
+	if err != nil {
+		return err
+	}
 	var result util.CommonError
 	err = json.Unmarshal(response, &result)
 	if err != nil {
 		return err
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("customer msg send error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("SendCustomerMessage", result.ErrCode, result.ErrMsg)
 		return err
 	}
 
@@ -5,8 +5,8 @@ type Image struct {
 	CommonToken
 
 	Image struct {
-		MediaID string `xml:"MediaId"`
-	} `xml:"Image"`
+		MediaID string `xml:"MediaId" json:"MediaId"`
+	} `xml:"Image" json:"Image"`
 }
 
 //NewImage 回复图片消息
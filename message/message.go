@@ -40,6 +40,10 @@ const (
 	MsgTypeTransfer = "transfer_customer_service"
 	//MsgTypeEvent 表示事件推送消息
 	MsgTypeEvent = "event"
+	//MsgTypeDeviceText 表示设备消息
+	MsgTypeDeviceText = "device_text"
+	//MsgTypeDeviceEvent 表示设备事件推送
+	MsgTypeDeviceEvent = "device_event"
 )
 
 const (
@@ -83,6 +87,44 @@ const (
 	EventAddExpressPath           = "add_express_path"            // 更新快递轨迹事件
 )
 
+const (
+	//EventCardPassCheck 卡券审核通过事件
+	EventCardPassCheck EventType = "card_pass_check"
+	//EventCardNotPassCheck 卡券审核未通过事件
+	EventCardNotPassCheck = "card_not_pass_check"
+	//EventUserGetCard 领取卡券事件
+	EventUserGetCard = "user_get_card"
+	//EventUserGiftingCard 转赠卡券事件
+	EventUserGiftingCard = "user_gifting_card"
+	//EventUserDelCard 删除卡券事件
+	EventUserDelCard = "user_del_card"
+	//EventUserConsumeCard 核销卡券事件
+	EventUserConsumeCard = "user_consume_card"
+	//EventUserPayFromPayCell 买单事件
+	EventUserPayFromPayCell = "user_pay_from_pay_cell"
+	//EventUserViewCard 进入会员卡事件
+	EventUserViewCard = "user_view_card"
+	//EventUserEnterSessionFromCard 从卡券进入公众号会话事件
+	EventUserEnterSessionFromCard = "user_enter_session_from_card"
+	//EventUpdateMemberCard 会员卡内容更新事件
+	EventUpdateMemberCard = "update_member_card"
+	//EventCardSkuRemind 卡券库存报警事件
+	EventCardSkuRemind = "card_sku_remind"
+	//EventSubmitMemberCardUserInfo 会员卡激活事件
+	EventSubmitMemberCardUserInfo = "submit_membercard_user_info"
+)
+
+const (
+	//EventDeviceBind 绑定设备事件, MsgType 为 device_event
+	EventDeviceBind EventType = "bind"
+	//EventDeviceUnbind 解绑设备事件, MsgType 为 device_event
+	EventDeviceUnbind = "unbind"
+	//EventDeviceSubscribeStatus 订阅设备状态事件, MsgType 为 device_event
+	EventDeviceSubscribeStatus = "subscribe_status"
+	//EventDeviceUnsubscribeStatus 退订设备状态事件, MsgType 为 device_event
+	EventDeviceUnsubscribeStatus = "unsubscribe_status"
+)
+
 const (
 	// InfoTypeVerifyTicket 返回ticket
 	InfoTypeVerifyTicket InfoType = "component_verify_ticket"
@@ -96,121 +138,126 @@ const (
 )
 
 //MixMessage 存放所有微信发送过来的消息和事件
+//不同消息和事件的字段都平铺在一起, 需要具体类型时可以使用 Typed 或 Decode
 type MixMessage struct {
 	CommonToken
 
 	//基本消息
-	MsgID        int64   `xml:"MsgId"`
-	Content      string  `xml:"Content"`
-	Recognition  string  `xml:"Recognition"`
-	PicURL       string  `xml:"PicUrl"`
-	MediaID      string  `xml:"MediaId"`
-	Format       string  `xml:"Format"`
-	ThumbMediaID string  `xml:"ThumbMediaId"`
-	LocationX    float64 `xml:"Location_X"`
-	LocationY    float64 `xml:"Location_Y"`
-	Scale        float64 `xml:"Scale"`
-	Label        string  `xml:"Label"`
-	Title        string  `xml:"Title"`
-	Description  string  `xml:"Description"`
-	URL          string  `xml:"Url"`
+	MsgID        int64   `xml:"MsgId" json:"MsgId"`
+	Content      string  `xml:"Content" json:"Content"`
+	Recognition  string  `xml:"Recognition" json:"Recognition"`
+	PicURL       string  `xml:"PicUrl" json:"PicUrl"`
+	MediaID      string  `xml:"MediaId" json:"MediaId"`
+	Format       string  `xml:"Format" json:"Format"`
+	ThumbMediaID string  `xml:"ThumbMediaId" json:"ThumbMediaId"`
+	LocationX    float64 `xml:"Location_X" json:"Location_X"`
+	LocationY    float64 `xml:"Location_Y" json:"Location_Y"`
+	Scale        float64 `xml:"Scale" json:"Scale"`
+	Label        string  `xml:"Label" json:"Label"`
+	Title        string  `xml:"Title" json:"Title"`
+	Description  string  `xml:"Description" json:"Description"`
+	URL          string  `xml:"Url" json:"Url"`
 
 	//事件相关
-	Event       EventType `xml:"Event"`
-	EventKey    string    `xml:"EventKey"`
-	Ticket      string    `xml:"Ticket"`
-	Latitude    string    `xml:"Latitude"`
-	Longitude   string    `xml:"Longitude"`
-	Precision   string    `xml:"Precision"`
-	MenuID      string    `xml:"MenuId"`
-	Status      string    `xml:"Status"`
-	SessionFrom string    `xml:"SessionFrom"`
+	Event       EventType `xml:"Event" json:"Event"`
+	EventKey    string    `xml:"EventKey" json:"EventKey"`
+	Ticket      string    `xml:"Ticket" json:"Ticket"`
+	Latitude    string    `xml:"Latitude" json:"Latitude"`
+	Longitude   string    `xml:"Longitude" json:"Longitude"`
+	Precision   string    `xml:"Precision" json:"Precision"`
+	MenuID      string    `xml:"MenuId" json:"MenuId"`
+	Status      string    `xml:"Status" json:"Status"`
+	SessionFrom string    `xml:"SessionFrom" json:"SessionFrom"`
 	// 审核第三方修改昵称事件
 	WXANickNameAuditEvent
 
 	ScanCodeInfo struct {
-		ScanType   string `xml:"ScanType"`
-		ScanResult string `xml:"ScanResult"`
-	} `xml:"ScanCodeInfo"`
+		ScanType   string `xml:"ScanType" json:"ScanType"`
+		ScanResult string `xml:"ScanResult" json:"ScanResult"`
+	} `xml:"ScanCodeInfo" json:"ScanCodeInfo"`
 
 	SendPicsInfo struct {
-		Count   int32      `xml:"Count"`
-		PicList []EventPic `xml:"PicList>item"`
-	} `xml:"SendPicsInfo"`
+		Count   int32      `xml:"Count" json:"Count"`
+		PicList []EventPic `xml:"PicList>item" json:"PicList"`
+	} `xml:"SendPicsInfo" json:"SendPicsInfo"`
 
 	SendLocationInfo struct {
-		LocationX float64 `xml:"Location_X"`
-		LocationY float64 `xml:"Location_Y"`
-		Scale     float64 `xml:"Scale"`
-		Label     string  `xml:"Label"`
-		Poiname   string  `xml:"Poiname"`
+		LocationX float64 `xml:"Location_X" json:"Location_X"`
+		LocationY float64 `xml:"Location_Y" json:"Location_Y"`
+		Scale     float64 `xml:"Scale" json:"Scale"`
+		Label     string  `xml:"Label" json:"Label"`
+		Poiname   string  `xml:"Poiname" json:"Poiname"`
 	}
 
 	// 第三方平台相关
-	InfoType                     InfoType `xml:"InfoType"`
-	AppID                        string   `xml:"AppId"`
-	ComponentVerifyTicket        string   `xml:"ComponentVerifyTicket"`
-	AuthorizerAppid              string   `xml:"AuthorizerAppid"`
-	AuthorizationCode            string   `xml:"AuthorizationCode"`
-	AuthorizationCodeExpiredTime int64    `xml:"AuthorizationCodeExpiredTime"`
-	PreAuthCode                  string   `xml:"PreAuthCode"`
+	InfoType                     InfoType `xml:"InfoType" json:"InfoType"`
+	AppID                        string   `xml:"AppId" json:"AppId"`
+	ComponentVerifyTicket        string   `xml:"ComponentVerifyTicket" json:"ComponentVerifyTicket"`
+	AuthorizerAppid              string   `xml:"AuthorizerAppid" json:"AuthorizerAppid"`
+	AuthorizationCode            string   `xml:"AuthorizationCode" json:"AuthorizationCode"`
+	AuthorizationCodeExpiredTime int64    `xml:"AuthorizationCodeExpiredTime" json:"AuthorizationCodeExpiredTime"`
+	PreAuthCode                  string   `xml:"PreAuthCode" json:"PreAuthCode"`
 	// 创建快速小程序需要用到的事件
-	CreateTime         int64  `xml:"CreateTime"`
-	CreatedAPPID       string `xml:"appid"`
-	AuthCode           string `xml:"auth_code"`
-	Msg                string `xml:"msg"`
-	FastRegisterStatus int32  `xml:"status"`
+	CreateTime         int64  `xml:"CreateTime" json:"CreateTime"`
+	CreatedAPPID       string `xml:"appid" json:"appid"`
+	AuthCode           string `xml:"auth_code" json:"auth_code"`
+	Msg                string `xml:"msg" json:"msg"`
+	FastRegisterStatus int32  `xml:"status" json:"status"`
 	FastRegisterInfo   struct {
-		Name               string `xml:"name"`
-		Code               string `xml:"code"`
-		CodeType           int32  `xml:"code_type"`
-		LegalPersonaWechat string `xml:"legal_persona_wechat"`
-		LegalPersonaName   string `xml:"legal_persona_name"`
-		ComponentPhone     string `xml:"component_phone"`
-	} `xml:"info"`
+		Name               string `xml:"name" json:"name"`
+		Code               string `xml:"code" json:"code"`
+		CodeType           int32  `xml:"code_type" json:"code_type"`
+		LegalPersonaWechat string `xml:"legal_persona_wechat" json:"legal_persona_wechat"`
+		LegalPersonaName   string `xml:"legal_persona_name" json:"legal_persona_name"`
+		ComponentPhone     string `xml:"component_phone" json:"component_phone"`
+	} `xml:"info" json:"info"`
 	// 审核相关事件内容
-	AuditSuccessTime  int64  `xml:"SuccTime"`
-	AuditFailedTime   int64  `xml:"FailTime"` //
-	AuditDelayTime    int64  `xml:"DelayTime"`
-	AuditFailedReason string `xml:"Reason"`     // 审核失败原因
-	AuditScreenShot   string `xml:"ScreenShot"` // 审核失败截图
+	AuditSuccessTime  int64  `xml:"SuccTime" json:"SuccTime"`
+	AuditFailedTime   int64  `xml:"FailTime" json:"FailTime"` //
+	AuditDelayTime    int64  `xml:"DelayTime" json:"DelayTime"`
+	AuditFailedReason string `xml:"Reason" json:"Reason"`         // 审核失败原因
+	AuditScreenShot   string `xml:"ScreenShot" json:"ScreenShot"` // 审核失败截图
 
 	// 卡券相关
-	CardID              string `xml:"CardId"`
-	RefuseReason        string `xml:"RefuseReason"`
-	IsGiveByFriend      int32  `xml:"IsGiveByFriend"`
-	FriendUserName      string `xml:"FriendUserName"`
-	UserCardCode        string `xml:"UserCardCode"`
-	OldUserCardCode     string `xml:"OldUserCardCode"`
-	OuterStr            string `xml:"OuterStr"`
-	IsRestoreMemberCard int32  `xml:"IsRestoreMemberCard"`
-	UnionID             string `xml:"UnionId"`
+	CardID              string `xml:"CardId" json:"CardId"`
+	RefuseReason        string `xml:"RefuseReason" json:"RefuseReason"`
+	IsGiveByFriend      int32  `xml:"IsGiveByFriend" json:"IsGiveByFriend"`
+	FriendUserName      string `xml:"FriendUserName" json:"FriendUserName"`
+	UserCardCode        string `xml:"UserCardCode" json:"UserCardCode"`
+	OldUserCardCode     string `xml:"OldUserCardCode" json:"OldUserCardCode"`
+	OuterStr            string `xml:"OuterStr" json:"OuterStr"`
+	IsRestoreMemberCard int32  `xml:"IsRestoreMemberCard" json:"IsRestoreMemberCard"`
+	UnionID             string `xml:"UnionId" json:"UnionId"`
 
 	// 内容审核相关
-	IsRisky       bool   `xml:"isrisky"`
-	ExtraInfoJSON string `xml:"extra_info_json"`
-	TraceID       string `xml:"trace_id"`
-	StatusCode    int    `xml:"status_code"`
+	IsRisky       bool   `xml:"isrisky" json:"isrisky"`
+	ExtraInfoJSON string `xml:"extra_info_json" json:"extra_info_json"`
+	TraceID       string `xml:"trace_id" json:"trace_id"`
+	StatusCode    int    `xml:"status_code" json:"status_code"`
 
 	//设备相关
 	device.MsgDevice
 
 	// 绑定快递账号事件
-	ErrCode     int32                            `xml:"errcode"`
-	ErrMsg      string                           `xml:"errmsg"`
-	DeliveryID  string                           `xml:"delivery_id"`
-	BizID       string                           `xml:"biz_id"`
-	DeliveryIDX string                           `xml:"DeliveryID"` //  DeliveryID vs delivery_id  此处要问鹅厂的童鞋
-	WayBillID   string                           `xml:"WayBillId"`  //  账单ID
-	OrderID     string                           `xml:"OrderId"`    //  订单ID
-	Version     int32                            `xml:"Version"`
-	Count       int32                            `xml:"Count"`
-	Actions     []*miniprogram.LogisticsPathItem `xml:"Actions" `
+	ErrCode     int32                            `xml:"errcode" json:"errcode"`
+	ErrMsg      string                           `xml:"errmsg" json:"errmsg"`
+	DeliveryID  string                           `xml:"delivery_id" json:"delivery_id"`
+	BizID       string                           `xml:"biz_id" json:"biz_id"`
+	DeliveryIDX string                           `xml:"DeliveryID" json:"DeliveryID"` //  DeliveryID vs delivery_id  此处要问鹅厂的童鞋
+	WayBillID   string                           `xml:"WayBillId" json:"WayBillId"`   //  账单ID
+	OrderID     string                           `xml:"OrderId" json:"OrderId"`       //  订单ID
+	Version     int32                            `xml:"Version" json:"Version"`
+	Count       int32                            `xml:"Count" json:"Count"`
+	Actions     []*miniprogram.LogisticsPathItem `xml:"Actions" json:"Actions"`
+
+	//原始消息, 用于 Typed 解析为具体类型
+	raw    []byte
+	isJSON bool
 }
 
 //EventPic 发图事件推送
 type EventPic struct {
-	PicMd5Sum string `xml:"PicMd5Sum"`
+	PicMd5Sum string `xml:"PicMd5Sum" json:"PicMd5Sum"`
 }
 
 //EncryptedXMLMsg 安全模式下的消息体
@@ -241,11 +288,11 @@ func (c CDATA) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 // CommonToken 消息中通用的结构
 type CommonToken struct {
-	XMLName      xml.Name `xml:"xml"`
-	ToUserName   CDATA    `xml:"ToUserName"`
-	FromUserName CDATA    `xml:"FromUserName"`
-	CreateTime   int64    `xml:"CreateTime"`
-	MsgType      MsgType  `xml:"MsgType"`
+	XMLName      xml.Name `xml:"xml" json:"-"`
+	ToUserName   CDATA    `xml:"ToUserName" json:"ToUserName"`
+	FromUserName CDATA    `xml:"FromUserName" json:"FromUserName"`
+	CreateTime   int64    `xml:"CreateTime" json:"CreateTime"`
+	MsgType      MsgType  `xml:"MsgType" json:"MsgType"`
 }
 
 //SetToUserName set ToUserName
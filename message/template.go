@@ -1,6 +1,7 @@
 package message
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 
@@ -24,6 +25,11 @@ func NewTemplate(context *context.Context) *Template {
 	return tpl
 }
 
+//WithContext 返回使用 ctx 发起请求的 Template，ctx 取消或超时后请求会被中止
+func (tpl *Template) WithContext(ctx gocontext.Context) *Template {
+	return NewTemplate(tpl.Context.WithContext(ctx))
+}
+
 //Message 发送的模板消息内容
 type Message struct {
 	ToUser     string               `json:"touser"`          // 必须, 接受者OpenID
@@ -58,7 +64,7 @@ func (tpl *Template) Send(msg *Message) (msgID int64, err error) {
 		return
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", templateSendURL, accessToken)
-	response, err := util.PostJSON(uri, msg)
+	response, err := tpl.HTTPClient().PostJSON(uri, msg)
 
 	var result resTemplateSend
 	err = json.Unmarshal(response, &result)
@@ -66,7 +72,7 @@ func (tpl *Template) Send(msg *Message) (msgID int64, err error) {
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("template msg send error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("SendTemplate", result.ErrCode, result.ErrMsg)
 		return
 	}
 	msgID = result.MsgID
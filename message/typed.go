@@ -0,0 +1,482 @@
+package message
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+
+	"github.com/antsbean/wechat/device"
+	"github.com/antsbean/wechat/miniprogram"
+)
+
+//ErrNoRawMessage MixMessage 没有保留原始消息, 无法解析为具体类型
+var ErrNoRawMessage = errors.New("没有原始消息")
+
+//EventHeader 事件推送中通用的结构
+type EventHeader struct {
+	CommonToken
+	Event EventType `xml:"Event" json:"Event"`
+}
+
+//ComponentHeader 第三方平台推送中通用的结构
+type ComponentHeader struct {
+	XMLName    xml.Name `xml:"xml" json:"-"`
+	AppID      string   `xml:"AppId" json:"AppId"`
+	CreateTime int64    `xml:"CreateTime" json:"CreateTime"`
+	InfoType   InfoType `xml:"InfoType" json:"InfoType"`
+}
+
+//TextMessage 文本消息
+type TextMessage struct {
+	CommonToken
+	MsgID   int64  `xml:"MsgId" json:"MsgId"`
+	Content string `xml:"Content" json:"Content"`
+}
+
+//ImageMessage 图片消息
+type ImageMessage struct {
+	CommonToken
+	MsgID   int64  `xml:"MsgId" json:"MsgId"`
+	PicURL  string `xml:"PicUrl" json:"PicUrl"`
+	MediaID string `xml:"MediaId" json:"MediaId"`
+}
+
+//VoiceMessage 语音消息
+type VoiceMessage struct {
+	CommonToken
+	MsgID       int64  `xml:"MsgId" json:"MsgId"`
+	MediaID     string `xml:"MediaId" json:"MediaId"`
+	Format      string `xml:"Format" json:"Format"`
+	Recognition string `xml:"Recognition" json:"Recognition"` //开通语音识别后的识别结果
+}
+
+//VideoMessage 视频消息以及小视频消息
+type VideoMessage struct {
+	CommonToken
+	MsgID        int64  `xml:"MsgId" json:"MsgId"`
+	MediaID      string `xml:"MediaId" json:"MediaId"`
+	ThumbMediaID string `xml:"ThumbMediaId" json:"ThumbMediaId"`
+}
+
+//LocationMessage 地理位置消息
+type LocationMessage struct {
+	CommonToken
+	MsgID     int64   `xml:"MsgId" json:"MsgId"`
+	LocationX float64 `xml:"Location_X" json:"Location_X"`
+	LocationY float64 `xml:"Location_Y" json:"Location_Y"`
+	Scale     float64 `xml:"Scale" json:"Scale"`
+	Label     string  `xml:"Label" json:"Label"`
+}
+
+//LinkMessage 链接消息
+type LinkMessage struct {
+	CommonToken
+	MsgID       int64  `xml:"MsgId" json:"MsgId"`
+	Title       string `xml:"Title" json:"Title"`
+	Description string `xml:"Description" json:"Description"`
+	URL         string `xml:"Url" json:"Url"`
+}
+
+//SubscribeEvent 关注事件, 扫描带参数二维码关注时 EventKey 为 qrscene_ 加上场景值
+type SubscribeEvent struct {
+	EventHeader
+	EventKey string `xml:"EventKey" json:"EventKey"`
+	Ticket   string `xml:"Ticket" json:"Ticket"`
+}
+
+//UnsubscribeEvent 取消关注事件
+type UnsubscribeEvent struct {
+	EventHeader
+}
+
+//ScanEvent 已关注用户扫描带参数二维码事件
+type ScanEvent struct {
+	EventHeader
+	EventKey string `xml:"EventKey" json:"EventKey"`
+	Ticket   string `xml:"Ticket" json:"Ticket"`
+}
+
+//LocationEvent 上报地理位置事件
+type LocationEvent struct {
+	EventHeader
+	Latitude  string `xml:"Latitude" json:"Latitude"`
+	Longitude string `xml:"Longitude" json:"Longitude"`
+	Precision string `xml:"Precision" json:"Precision"`
+}
+
+//ClickEvent 点击菜单拉取消息事件
+type ClickEvent struct {
+	EventHeader
+	EventKey string `xml:"EventKey" json:"EventKey"`
+}
+
+//ViewEvent 点击菜单跳转链接事件
+type ViewEvent struct {
+	EventHeader
+	EventKey string `xml:"EventKey" json:"EventKey"`
+	MenuID   string `xml:"MenuId" json:"MenuId"`
+}
+
+//ScancodeEvent 扫码推事件以及扫码推事件且弹出“消息接收中”提示框事件
+type ScancodeEvent struct {
+	EventHeader
+	EventKey     string `xml:"EventKey" json:"EventKey"`
+	ScanCodeInfo struct {
+		ScanType   string `xml:"ScanType" json:"ScanType"`
+		ScanResult string `xml:"ScanResult" json:"ScanResult"`
+	} `xml:"ScanCodeInfo" json:"ScanCodeInfo"`
+}
+
+//PicEvent 弹出系统拍照发图、拍照或者相册发图、微信相册发图器事件
+type PicEvent struct {
+	EventHeader
+	EventKey     string `xml:"EventKey" json:"EventKey"`
+	SendPicsInfo struct {
+		Count   int32      `xml:"Count" json:"Count"`
+		PicList []EventPic `xml:"PicList>item" json:"PicList"`
+	} `xml:"SendPicsInfo" json:"SendPicsInfo"`
+}
+
+//LocationSelectEvent 弹出地理位置选择器事件
+type LocationSelectEvent struct {
+	EventHeader
+	EventKey         string `xml:"EventKey" json:"EventKey"`
+	SendLocationInfo struct {
+		LocationX float64 `xml:"Location_X" json:"Location_X"`
+		LocationY float64 `xml:"Location_Y" json:"Location_Y"`
+		Scale     float64 `xml:"Scale" json:"Scale"`
+		Label     string  `xml:"Label" json:"Label"`
+		Poiname   string  `xml:"Poiname" json:"Poiname"`
+	} `xml:"SendLocationInfo" json:"SendLocationInfo"`
+}
+
+//TemplateSendJobFinishEvent 模板消息发送结果事件
+type TemplateSendJobFinishEvent struct {
+	EventHeader
+	MsgID  int64  `xml:"MsgID" json:"MsgID"`
+	Status string `xml:"Status" json:"Status"`
+}
+
+//WxaMediaCheckEvent 异步校验图片/音频内容安全的结果事件
+type WxaMediaCheckEvent struct {
+	EventHeader
+	AppID         string `xml:"appid" json:"appid"`
+	IsRisky       bool   `xml:"isrisky" json:"isrisky"`
+	ExtraInfoJSON string `xml:"extra_info_json" json:"extra_info_json"`
+	TraceID       string `xml:"trace_id" json:"trace_id"`
+	StatusCode    int    `xml:"status_code" json:"status_code"`
+}
+
+//WxaNicknameAuditEvent 小程序名称审核结果事件
+type WxaNicknameAuditEvent struct {
+	EventHeader
+	WXANickNameAuditEvent
+}
+
+//AppAuditSuccessEvent 小程序代码审核通过事件
+type AppAuditSuccessEvent struct {
+	EventHeader
+	SuccTime int64 `xml:"SuccTime" json:"SuccTime"`
+}
+
+//AppAuditFailedEvent 小程序代码审核失败事件
+type AppAuditFailedEvent struct {
+	EventHeader
+	Reason     string `xml:"Reason" json:"Reason"`
+	FailTime   int64  `xml:"FailTime" json:"FailTime"`
+	ScreenShot string `xml:"ScreenShot" json:"ScreenShot"`
+}
+
+//AppAuditDelayEvent 小程序代码审核延后事件
+type AppAuditDelayEvent struct {
+	EventHeader
+	Reason    string `xml:"Reason" json:"Reason"`
+	DelayTime int64  `xml:"DelayTime" json:"DelayTime"`
+}
+
+//UpdateBusinessBindResultEvent 绑定快递账号结果事件
+type UpdateBusinessBindResultEvent struct {
+	EventHeader
+	ErrCode    int32  `xml:"errcode" json:"errcode"`
+	ErrMsg     string `xml:"errmsg" json:"errmsg"`
+	DeliveryID string `xml:"delivery_id" json:"delivery_id"`
+	BizID      string `xml:"biz_id" json:"biz_id"`
+}
+
+//AddExpressPathEvent 运单轨迹更新事件
+type AddExpressPathEvent struct {
+	EventHeader
+	DeliveryID string                           `xml:"DeliveryID" json:"DeliveryID"`
+	WayBillID  string                           `xml:"WayBillId" json:"WayBillId"`
+	OrderID    string                           `xml:"OrderId" json:"OrderId"`
+	Version    int32                            `xml:"Version" json:"Version"`
+	Count      int32                            `xml:"Count" json:"Count"`
+	Actions    []*miniprogram.LogisticsPathItem `xml:"Actions" json:"Actions"`
+}
+
+//CardCheckEvent 卡券审核通过以及审核未通过事件
+type CardCheckEvent struct {
+	EventHeader
+	CardID       string `xml:"CardId" json:"CardId"`
+	RefuseReason string `xml:"RefuseReason" json:"RefuseReason"`
+}
+
+//UserGetCardEvent 领取卡券事件
+type UserGetCardEvent struct {
+	EventHeader
+	CardID              string `xml:"CardId" json:"CardId"`
+	UserCardCode        string `xml:"UserCardCode" json:"UserCardCode"`
+	IsGiveByFriend      int32  `xml:"IsGiveByFriend" json:"IsGiveByFriend"`
+	FriendUserName      string `xml:"FriendUserName" json:"FriendUserName"`
+	OldUserCardCode     string `xml:"OldUserCardCode" json:"OldUserCardCode"`
+	OuterID             int64  `xml:"OuterId" json:"OuterId"`
+	OuterStr            string `xml:"OuterStr" json:"OuterStr"`
+	IsRestoreMemberCard int32  `xml:"IsRestoreMemberCard" json:"IsRestoreMemberCard"`
+	UnionID             string `xml:"UnionId" json:"UnionId"`
+}
+
+//UserGiftingCardEvent 转赠卡券事件
+type UserGiftingCardEvent struct {
+	EventHeader
+	CardID         string `xml:"CardId" json:"CardId"`
+	UserCardCode   string `xml:"UserCardCode" json:"UserCardCode"`
+	FriendUserName string `xml:"FriendUserName" json:"FriendUserName"`
+	IsReturnBack   int32  `xml:"IsReturnBack" json:"IsReturnBack"`
+	IsChatRoom     int32  `xml:"IsChatRoom" json:"IsChatRoom"`
+}
+
+//UserCardEvent 删除卡券、从卡券进入公众号会话以及会员卡激活事件
+type UserCardEvent struct {
+	EventHeader
+	CardID       string `xml:"CardId" json:"CardId"`
+	UserCardCode string `xml:"UserCardCode" json:"UserCardCode"`
+}
+
+//UserConsumeCardEvent 核销卡券事件
+type UserConsumeCardEvent struct {
+	EventHeader
+	CardID        string `xml:"CardId" json:"CardId"`
+	UserCardCode  string `xml:"UserCardCode" json:"UserCardCode"`
+	ConsumeSource string `xml:"ConsumeSource" json:"ConsumeSource"`
+	LocationName  string `xml:"LocationName" json:"LocationName"`
+	StaffOpenID   string `xml:"StaffOpenId" json:"StaffOpenId"`
+	VerifyCode    string `xml:"VerifyCode" json:"VerifyCode"`
+	RemarkAmount  string `xml:"RemarkAmount" json:"RemarkAmount"`
+	OuterStr      string `xml:"OuterStr" json:"OuterStr"`
+}
+
+//UserPayFromPayCellEvent 买单事件, 金额的单位为分
+type UserPayFromPayCellEvent struct {
+	EventHeader
+	CardID       string `xml:"CardId" json:"CardId"`
+	UserCardCode string `xml:"UserCardCode" json:"UserCardCode"`
+	TransID      string `xml:"TransId" json:"TransId"`
+	LocationID   int64  `xml:"LocationId" json:"LocationId"`
+	Fee          int64  `xml:"Fee" json:"Fee"`
+	OriginalFee  int64  `xml:"OriginalFee" json:"OriginalFee"`
+}
+
+//UserViewCardEvent 进入会员卡事件
+type UserViewCardEvent struct {
+	EventHeader
+	CardID       string `xml:"CardId" json:"CardId"`
+	UserCardCode string `xml:"UserCardCode" json:"UserCardCode"`
+	OuterStr     string `xml:"OuterStr" json:"OuterStr"`
+}
+
+//UpdateMemberCardEvent 会员卡积分、余额变更事件
+type UpdateMemberCardEvent struct {
+	EventHeader
+	CardID        string `xml:"CardId" json:"CardId"`
+	UserCardCode  string `xml:"UserCardCode" json:"UserCardCode"`
+	ModifyBonus   int64  `xml:"ModifyBonus" json:"ModifyBonus"`
+	ModifyBalance int64  `xml:"ModifyBalance" json:"ModifyBalance"`
+}
+
+//CardSkuRemindEvent 卡券库存报警事件
+type CardSkuRemindEvent struct {
+	EventHeader
+	CardID string `xml:"CardId" json:"CardId"`
+	Detail string `xml:"Detail" json:"Detail"`
+}
+
+//DeviceTextMessage 设备消息, Content 为设备发送的数据经过 base64 编码后的内容
+type DeviceTextMessage struct {
+	CommonToken
+	device.MsgDevice
+	MsgID   int64  `xml:"MsgId" json:"MsgId"`
+	Content string `xml:"Content" json:"Content"`
+}
+
+//DeviceEvent 绑定以及解绑设备事件
+type DeviceEvent struct {
+	EventHeader
+	device.MsgDevice
+	Content string `xml:"Content" json:"Content"`
+}
+
+//DeviceStatusEvent 订阅以及退订设备状态事件, OpType 为 0 时退订, 为 1 时订阅
+type DeviceStatusEvent struct {
+	EventHeader
+	device.MsgDevice
+	OpType int32 `xml:"OpType" json:"OpType"`
+}
+
+//ComponentVerifyTicketEvent 第三方平台推送 component_verify_ticket
+type ComponentVerifyTicketEvent struct {
+	ComponentHeader
+	ComponentVerifyTicket string `xml:"ComponentVerifyTicket" json:"ComponentVerifyTicket"`
+}
+
+//AuthorizedEvent 第三方平台授权成功以及授权更新事件
+type AuthorizedEvent struct {
+	ComponentHeader
+	AuthorizerAppid              string `xml:"AuthorizerAppid" json:"AuthorizerAppid"`
+	AuthorizationCode            string `xml:"AuthorizationCode" json:"AuthorizationCode"`
+	AuthorizationCodeExpiredTime int64  `xml:"AuthorizationCodeExpiredTime" json:"AuthorizationCodeExpiredTime"`
+	PreAuthCode                  string `xml:"PreAuthCode" json:"PreAuthCode"`
+}
+
+//UnauthorizedEvent 第三方平台取消授权事件
+type UnauthorizedEvent struct {
+	ComponentHeader
+	AuthorizerAppid string `xml:"AuthorizerAppid" json:"AuthorizerAppid"`
+}
+
+//FastRegisterEvent 第三方平台快速创建小程序的结果事件
+type FastRegisterEvent struct {
+	ComponentHeader
+	CreatedAppID string `xml:"appid" json:"appid"`
+	Status       int32  `xml:"status" json:"status"`
+	AuthCode     string `xml:"auth_code" json:"auth_code"`
+	Msg          string `xml:"msg" json:"msg"`
+	Info         struct {
+		Name               string `xml:"name" json:"name"`
+		Code               string `xml:"code" json:"code"`
+		CodeType           int32  `xml:"code_type" json:"code_type"`
+		LegalPersonaWechat string `xml:"legal_persona_wechat" json:"legal_persona_wechat"`
+		LegalPersonaName   string `xml:"legal_persona_name" json:"legal_persona_name"`
+		ComponentPhone     string `xml:"component_phone" json:"component_phone"`
+	} `xml:"info" json:"info"`
+}
+
+var msgTypes = map[MsgType]func() interface{}{
+	MsgTypeText:       func() interface{} { return new(TextMessage) },
+	MsgTypeImage:      func() interface{} { return new(ImageMessage) },
+	MsgTypeVoice:      func() interface{} { return new(VoiceMessage) },
+	MsgTypeVideo:      func() interface{} { return new(VideoMessage) },
+	MsgTypeShortVideo: func() interface{} { return new(VideoMessage) },
+	MsgTypeLocation:   func() interface{} { return new(LocationMessage) },
+	MsgTypeLink:       func() interface{} { return new(LinkMessage) },
+	MsgTypeDeviceText: func() interface{} { return new(DeviceTextMessage) },
+}
+
+var eventTypes = map[EventType]func() interface{}{
+	EventSubscribe:                func() interface{} { return new(SubscribeEvent) },
+	EventUnsubscribe:              func() interface{} { return new(UnsubscribeEvent) },
+	EventScan:                     func() interface{} { return new(ScanEvent) },
+	EventLocation:                 func() interface{} { return new(LocationEvent) },
+	EventClick:                    func() interface{} { return new(ClickEvent) },
+	EventView:                     func() interface{} { return new(ViewEvent) },
+	EventScancodePush:             func() interface{} { return new(ScancodeEvent) },
+	EventScancodeWaitmsg:          func() interface{} { return new(ScancodeEvent) },
+	EventPicSysphoto:              func() interface{} { return new(PicEvent) },
+	EventPicPhotoOrAlbum:          func() interface{} { return new(PicEvent) },
+	EventPicWeixin:                func() interface{} { return new(PicEvent) },
+	EventLocationSelect:           func() interface{} { return new(LocationSelectEvent) },
+	EventTemplateSendJobFinish:    func() interface{} { return new(TemplateSendJobFinishEvent) },
+	EventWxaMediaCheck:            func() interface{} { return new(WxaMediaCheckEvent) },
+	EventWxaNicknameAudit:         func() interface{} { return new(WxaNicknameAuditEvent) },
+	EventAppAuditSuccess:          func() interface{} { return new(AppAuditSuccessEvent) },
+	EventAppAuditFailed:           func() interface{} { return new(AppAuditFailedEvent) },
+	EventAppAuditDelay:            func() interface{} { return new(AppAuditDelayEvent) },
+	EventUpdateBusinessBindResult: func() interface{} { return new(UpdateBusinessBindResultEvent) },
+	EventAddExpressPath:           func() interface{} { return new(AddExpressPathEvent) },
+	EventCardPassCheck:            func() interface{} { return new(CardCheckEvent) },
+	EventCardNotPassCheck:         func() interface{} { return new(CardCheckEvent) },
+	EventUserGetCard:              func() interface{} { return new(UserGetCardEvent) },
+	EventUserGiftingCard:          func() interface{} { return new(UserGiftingCardEvent) },
+	EventUserDelCard:              func() interface{} { return new(UserCardEvent) },
+	EventUserConsumeCard:          func() interface{} { return new(UserConsumeCardEvent) },
+	EventUserPayFromPayCell:       func() interface{} { return new(UserPayFromPayCellEvent) },
+	EventUserViewCard:             func() interface{} { return new(UserViewCardEvent) },
+	EventUserEnterSessionFromCard: func() interface{} { return new(UserCardEvent) },
+	EventUpdateMemberCard:         func() interface{} { return new(UpdateMemberCardEvent) },
+	EventCardSkuRemind:            func() interface{} { return new(CardSkuRemindEvent) },
+	EventSubmitMemberCardUserInfo: func() interface{} { return new(UserCardEvent) },
+}
+
+//deviceEventTypes MsgType 为 device_event 的设备事件
+var deviceEventTypes = map[EventType]func() interface{}{
+	EventDeviceBind:              func() interface{} { return new(DeviceEvent) },
+	EventDeviceUnbind:            func() interface{} { return new(DeviceEvent) },
+	EventDeviceSubscribeStatus:   func() interface{} { return new(DeviceStatusEvent) },
+	EventDeviceUnsubscribeStatus: func() interface{} { return new(DeviceStatusEvent) },
+}
+
+var infoTypes = map[InfoType]func() interface{}{
+	InfoTypeVerifyTicket:     func() interface{} { return new(ComponentVerifyTicketEvent) },
+	InfoTypeAuthorized:       func() interface{} { return new(AuthorizedEvent) },
+	InfoTypeUpdateAuthorized: func() interface{} { return new(AuthorizedEvent) },
+	InfoTypeUnauthorized:     func() interface{} { return new(UnauthorizedEvent) },
+	NotifyThirdFasteRegister: func() interface{} { return new(FastRegisterEvent) },
+}
+
+//Decode 根据 MsgType、Event、InfoType 将明文消息解析为对应类型的结构体指针, 如 *TextMessage、*SubscribeEvent
+//无法识别的类型返回 *MixMessage, isJSON 表示消息是否为 json 格式
+func Decode(data []byte, isJSON bool) (interface{}, error) {
+	unmarshal := xml.Unmarshal
+	if isJSON {
+		unmarshal = json.Unmarshal
+	}
+
+	var header struct {
+		MsgType  MsgType   `xml:"MsgType" json:"MsgType"`
+		Event    EventType `xml:"Event" json:"Event"`
+		InfoType InfoType  `xml:"InfoType" json:"InfoType"`
+	}
+	if err := unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	var newMsg func() interface{}
+	switch {
+	case header.InfoType != "":
+		newMsg = infoTypes[header.InfoType]
+	case header.MsgType == MsgTypeEvent:
+		newMsg = eventTypes[header.Event]
+	case header.MsgType == MsgTypeDeviceEvent:
+		newMsg = deviceEventTypes[header.Event]
+	default:
+		newMsg = msgTypes[header.MsgType]
+	}
+	if newMsg == nil {
+		newMsg = func() interface{} { return new(MixMessage) }
+	}
+	msg := newMsg()
+	if err := unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+//DecodeMixMessage 将明文消息解析为 MixMessage, 并保留原始消息以便通过 MixMessage.Typed 解析为具体类型
+func DecodeMixMessage(data []byte, isJSON bool) (msg MixMessage, err error) {
+	if isJSON {
+		err = json.Unmarshal(data, &msg)
+	} else {
+		err = xml.Unmarshal(data, &msg)
+	}
+	msg.raw = data
+	msg.isJSON = isJSON
+	return
+}
+
+//Typed 将消息解析为对应类型的结构体指针, 见 Decode
+//只有通过 DecodeMixMessage 得到的 MixMessage 保留了原始消息, 否则返回 ErrNoRawMessage
+func (msg MixMessage) Typed() (interface{}, error) {
+	if msg.raw == nil {
+		return nil, ErrNoRawMessage
+	}
+	return Decode(msg.raw, msg.isJSON)
+}
@@ -0,0 +1,170 @@
+package message
+
+import (
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		data   string
+		isJSON bool
+		check  func(msg interface{}) bool
+	}{
+		{
+			`<xml><ToUserName><![CDATA[gh_test]]></ToUserName><FromUserName><![CDATA[user]]></FromUserName><CreateTime>1500000000</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[hi]]></Content><MsgId>1</MsgId></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*TextMessage)
+				return ok && m.Content == "hi" && m.MsgID == 1 && m.FromUserName == "user" && m.CreateTime == 1500000000
+			},
+		},
+		{
+			`<xml><FromUserName>user</FromUserName><MsgType>event</MsgType><Event>subscribe</Event><EventKey>qrscene_1</EventKey></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*SubscribeEvent)
+				return ok && m.Event == EventSubscribe && m.EventKey == "qrscene_1"
+			},
+		},
+		{
+			`<xml><MsgType>event</MsgType><Event>TEMPLATESENDJOBFINISH</Event><MsgID>200163836</MsgID><Status>success</Status></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*TemplateSendJobFinishEvent)
+				return ok && m.MsgID == 200163836 && m.Status == "success"
+			},
+		},
+		{
+			`<xml><MsgType>event</MsgType><Event>add_express_path</Event><DeliveryID>SF</DeliveryID><WayBillId>123</WayBillId><Actions><ActionTime>1</ActionTime><ActionMsg>a</ActionMsg></Actions><Actions><ActionTime>2</ActionTime></Actions></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*AddExpressPathEvent)
+				return ok && m.DeliveryID == "SF" && m.WayBillID == "123" && len(m.Actions) == 2
+			},
+		},
+		{
+			`<xml><AppId>component</AppId><CreateTime>1500000000</CreateTime><InfoType>authorized</InfoType><AuthorizerAppid>appid</AuthorizerAppid><AuthorizationCode>code</AuthorizationCode></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*AuthorizedEvent)
+				return ok && m.AppID == "component" && m.AuthorizerAppid == "appid" && m.AuthorizationCode == "code"
+			},
+		},
+		{
+			`<xml><FromUserName>user</FromUserName><MsgType>event</MsgType><Event>user_get_card</Event><CardId>card</CardId><UserCardCode>12312312</UserCardCode><IsGiveByFriend>1</IsGiveByFriend><FriendUserName>friend</FriendUserName><OuterStr>outer</OuterStr><UnionId>union</UnionId></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*UserGetCardEvent)
+				return ok && m.CardID == "card" && m.UserCardCode == "12312312" && m.IsGiveByFriend == 1 && m.FriendUserName == "friend" && m.OuterStr == "outer" && m.UnionID == "union"
+			},
+		},
+		{
+			`<xml><FromUserName>user</FromUserName><MsgType>event</MsgType><Event>user_consume_card</Event><CardId>card</CardId><UserCardCode>12312312</UserCardCode><ConsumeSource>FROM_API</ConsumeSource><StaffOpenId>staff</StaffOpenId></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*UserConsumeCardEvent)
+				return ok && m.CardID == "card" && m.ConsumeSource == "FROM_API" && m.StaffOpenID == "staff"
+			},
+		},
+		{
+			`<xml><MsgType>event</MsgType><Event>card_not_pass_check</Event><CardId>card</CardId><RefuseReason>reason</RefuseReason></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*CardCheckEvent)
+				return ok && m.Event == EventCardNotPassCheck && m.RefuseReason == "reason"
+			},
+		},
+		{
+			`<xml><MsgType>event</MsgType><Event>user_del_card</Event><CardId>card</CardId><UserCardCode>12312312</UserCardCode></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*UserCardEvent)
+				return ok && m.Event == EventUserDelCard && m.UserCardCode == "12312312"
+			},
+		},
+		{
+			`<xml><MsgType>event</MsgType><Event>update_member_card</Event><CardId>card</CardId><ModifyBonus>3</ModifyBonus><ModifyBalance>-5</ModifyBalance></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*UpdateMemberCardEvent)
+				return ok && m.ModifyBonus == 3 && m.ModifyBalance == -5
+			},
+		},
+		{
+			`<xml><FromUserName>user</FromUserName><MsgType>device_text</MsgType><DeviceType>gh_device</DeviceType><DeviceID>dev1</DeviceID><Content>aGk=</Content><SessionID>9</SessionID><MsgId>1</MsgId><OpenID>openid</OpenID></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*DeviceTextMessage)
+				return ok && m.DeviceID == "dev1" && m.Content == "aGk=" && m.SessionID == "9" && m.MsgID == 1 && m.OpenID == "openid"
+			},
+		},
+		{
+			`<xml><MsgType>device_event</MsgType><Event>bind</Event><DeviceType>gh_device</DeviceType><DeviceID>dev1</DeviceID><Content>aGk=</Content><OpenID>openid</OpenID></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*DeviceEvent)
+				return ok && m.Event == EventDeviceBind && m.DeviceID == "dev1" && m.Content == "aGk="
+			},
+		},
+		{
+			`<xml><MsgType>device_event</MsgType><Event>subscribe_status</Event><DeviceType>gh_device</DeviceType><DeviceID>dev1</DeviceID><OpType>1</OpType><OpenID>openid</OpenID></xml>`,
+			false,
+			func(msg interface{}) bool {
+				m, ok := msg.(*DeviceStatusEvent)
+				return ok && m.DeviceID == "dev1" && m.OpType == 1
+			},
+		},
+		{
+			`<xml><MsgType>event</MsgType><Event>bind</Event></xml>`,
+			false,
+			func(msg interface{}) bool {
+				_, ok := msg.(*MixMessage)
+				return ok
+			},
+		},
+		{
+			`{"ToUserName":"gh_test","FromUserName":"user","MsgType":"event","Event":"user_enter_tempsession","SessionFrom":"from"}`,
+			true,
+			func(msg interface{}) bool {
+				m, ok := msg.(*MixMessage)
+				return ok && m.SessionFrom == "from"
+			},
+		},
+		{
+			`{"ToUserName":"gh_test","FromUserName":"user","MsgType":"image","PicUrl":"http://example.com","MediaId":"media"}`,
+			true,
+			func(msg interface{}) bool {
+				m, ok := msg.(*ImageMessage)
+				return ok && m.PicURL == "http://example.com" && m.MediaID == "media"
+			},
+		},
+	}
+	for _, tt := range tests {
+		msg, err := Decode([]byte(tt.data), tt.isJSON)
+		if err != nil {
+			t.Errorf("decode %s error: %v", tt.data, err)
+			continue
+		}
+		if !tt.check(msg) {
+			t.Errorf("unexpected message %#v for %s", msg, tt.data)
+		}
+	}
+}
+
+func TestMixMessageTyped(t *testing.T) {
+	mix, err := DecodeMixMessage([]byte(`<xml><MsgType>event</MsgType><Event>CLICK</Event><EventKey>KEY</EventKey></xml>`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mix.EventKey != "KEY" {
+		t.Errorf("unexpected MixMessage %+v", mix)
+	}
+	msg, err := mix.Typed()
+	if click, ok := msg.(*ClickEvent); err != nil || !ok || click.EventKey != "KEY" {
+		t.Errorf("unexpected typed message %#v, err=%v", msg, err)
+	}
+
+	if _, err := (MixMessage{}).Typed(); err != ErrNoRawMessage {
+		t.Errorf("expect ErrNoRawMessage, got %v", err)
+	}
+}
@@ -5,8 +5,8 @@ type Voice struct {
 	CommonToken
 
 	Voice struct {
-		MediaID string `xml:"MediaId"`
-	} `xml:"Voice"`
+		MediaID string `xml:"MediaId" json:"MediaId"`
+	} `xml:"Voice" json:"Voice"`
 }
 
 //NewVoice 回复语音消息
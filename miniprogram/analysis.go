@@ -38,7 +38,7 @@ func (wxa *MiniProgram) fetchData(urlStr string, body interface{}) (response []b
 		return
 	}
 	urlStr = fmt.Sprintf(urlStr, accessToken)
-	response, err = util.PostJSON(urlStr, body)
+	response, err = wxa.HTTPClient().PostJSON(urlStr, body)
 	return
 }
 
@@ -71,7 +71,7 @@ func (wxa *MiniProgram) getAnalysisRetain(urlStr string, beginDate, endDate stri
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("getAnalysisRetain error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetAnalysisRetain", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -113,13 +113,13 @@ func (wxa *MiniProgram) GetAnalysisDailySummary(beginDate, endDate string) (resu
 	if err != nil {
 		return
 	}
-	fmt.Println(string(response))
+	wxa.Logger().Debugf("GetAnalysisDailySummary response = %s", response)
 	err = json.Unmarshal(response, &result)
 	if err != nil {
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetAnalysisDailySummary error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetAnalysisDailySummary", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -155,7 +155,7 @@ func (wxa *MiniProgram) getAnalysisVisitTrend(urlStr string, beginDate, endDate
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("getAnalysisVisitTrend error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetAnalysisVisitTrend", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -217,7 +217,7 @@ func (wxa *MiniProgram) GetAnalysisUserPortrait(beginDate, endDate string) (resu
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetAnalysisUserPortrait error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetAnalysisUserPortrait", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -258,7 +258,7 @@ func (wxa *MiniProgram) GetAnalysisVisitDistribution(beginDate, endDate string)
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetAnalysisVisitDistribution error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetAnalysisVisitDistribution", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -298,7 +298,7 @@ func (wxa *MiniProgram) GetAnalysisVisitPage(beginDate, endDate string) (result
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetAnalysisVisitPage error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetAnalysisVisitPage", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -43,7 +43,7 @@ func (wxa *MiniProgram) LogisticsBindAccount(account *LogisticsBindAccount) (com
 		return
 	}
 	urlStr := fmt.Sprintf(bindAccountURL, accessToken)
-	resultData, err := util.PostJSON(urlStr, account)
+	resultData, err := wxa.HTTPClient().PostJSON(urlStr, account)
 	if err != nil {
 		return
 	}
@@ -87,7 +87,7 @@ func (wxa *MiniProgram) LogisticsGetAllAccount() (accounts []*LogisticsAccount,
 		return
 	}
 	urlStr := fmt.Sprintf(getAllAccountURL, accessToken)
-	resultData, err := util.HTTPGet(urlStr)
+	resultData, err := wxa.HTTPClient().HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -100,7 +100,7 @@ func (wxa *MiniProgram) LogisticsGetAllAccount() (accounts []*LogisticsAccount,
 		return
 	}
 	if result.ErrCode != 0 {
-		err = result.Error("LogisticsGetAllAccount")
+		err = result.Err("LogisticsGetAllAccount")
 		return
 	}
 	accounts = result.Accounts
@@ -126,7 +126,7 @@ func (wxa *MiniProgram) LogisticsGetAllDelivery() (accounts []*Delivery, count i
 		return
 	}
 	urlStr := fmt.Sprintf(getAllDeliveryURL, accessToken)
-	resultData, err := util.HTTPGet(urlStr)
+	resultData, err := wxa.HTTPClient().HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -139,7 +139,7 @@ func (wxa *MiniProgram) LogisticsGetAllDelivery() (accounts []*Delivery, count i
 		return
 	}
 	if result.ErrCode != 0 {
-		err = result.Error("LogisticsGetAllAccount")
+		err = result.Err("LogisticsGetAllAccount")
 		return
 	}
 	accounts = result.Deliveries
@@ -185,7 +185,7 @@ func (wxa *MiniProgram) LogisticsGetPath(request *LogisticsPathRequest) (items [
 		return
 	}
 	urlStr := fmt.Sprintf(getPathURL, accessToken)
-	resultData, err := util.PostJSON(urlStr, request)
+	resultData, err := wxa.HTTPClient().PostJSON(urlStr, request)
 	if err != nil {
 		return
 	}
@@ -293,7 +293,7 @@ func (wxa *MiniProgram) LogisticsAddOrder(request *LogisticsOrderRequest) (*Logi
 		return nil, err
 	}
 	urlStr := fmt.Sprintf(addOrderURL, accessToken)
-	resultData, err := util.PostJSON(urlStr, request)
+	resultData, err := wxa.HTTPClient().PostJSON(urlStr, request)
 	if err != nil {
 		return nil, err
 	}
@@ -320,7 +320,7 @@ func (wxa *MiniProgram) LogisticsCancelOrder(request *CancelOrderRequest) (*Deli
 		return nil, err
 	}
 	urlStr := fmt.Sprintf(cancelOrderURL, accessToken)
-	resultData, err := util.PostJSON(urlStr, request)
+	resultData, err := wxa.HTTPClient().PostJSON(urlStr, request)
 	if err != nil {
 		return nil, err
 	}
@@ -339,7 +339,7 @@ func (wxa *MiniProgram) GetQuota(deliveryID, bizID string) (int32, error) {
 		return 0, err
 	}
 	urlStr := fmt.Sprintf(getQuotaURL, accessToken)
-	resultData, err := util.PostJSON(urlStr, map[string]string{"delivery_id": deliveryID, "biz_id": bizID})
+	resultData, err := wxa.HTTPClient().PostJSON(urlStr, map[string]string{"delivery_id": deliveryID, "biz_id": bizID})
 	if err != nil {
 		return 0, err
 	}
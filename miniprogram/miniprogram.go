@@ -1,6 +1,8 @@
 package miniprogram
 
 import (
+	gocontext "context"
+
 	"github.com/antsbean/wechat/context"
 )
 
@@ -15,3 +17,8 @@ func NewMiniProgram(context *context.Context) *MiniProgram {
 	miniProgram.Context = context
 	return miniProgram
 }
+
+// WithContext 返回使用 ctx 发起请求的 MiniProgram，ctx 取消或超时后请求会被中止
+func (wxa *MiniProgram) WithContext(ctx gocontext.Context) *MiniProgram {
+	return NewMiniProgram(wxa.Context.WithContext(ctx))
+}
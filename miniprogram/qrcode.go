@@ -49,7 +49,7 @@ func (wxa *MiniProgram) fetchCode(urlStr string, body interface{}) (response []b
 
 	urlStr = fmt.Sprintf(urlStr, accessToken)
 	var contentType string
-	response, contentType, err = util.PostJSONWithRespContentType(urlStr, body)
+	response, contentType, err = wxa.HTTPClient().PostJSONWithRespContentType(urlStr, body)
 	if err != nil {
 		return
 	}
@@ -58,7 +58,7 @@ func (wxa *MiniProgram) fetchCode(urlStr string, body interface{}) (response []b
 		var result util.CommonError
 		err = json.Unmarshal(response, &result)
 		if err == nil && result.ErrCode != 0 {
-			err = fmt.Errorf("fetchCode error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+			err = util.NewAPIError("FetchCode", result.ErrCode, result.ErrMsg)
 			return nil, err
 		}
 	} else if contentType == "image/jpeg" {
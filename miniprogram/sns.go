@@ -24,7 +24,7 @@ type ResCode2Session struct {
 func (wxa *MiniProgram) Code2Session(jsCode string) (result ResCode2Session, err error) {
 	urlStr := fmt.Sprintf(code2SessionURL, wxa.AppID, wxa.AppSecret, jsCode)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = wxa.HTTPClient().HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -33,7 +33,7 @@ func (wxa *MiniProgram) Code2Session(jsCode string) (result ResCode2Session, err
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("Code2Session error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("Code2Session", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -31,6 +32,11 @@ func NewOauth(context *context.Context) *Oauth {
 	return auth
 }
 
+//WithContext 返回使用 ctx 发起请求的 Oauth，ctx 取消或超时后请求会被中止
+func (oauth *Oauth) WithContext(ctx gocontext.Context) *Oauth {
+	return NewOauth(oauth.Context.WithContext(ctx))
+}
+
 //GetRedirectURL 获取跳转的url地址
 func (oauth *Oauth) GetRedirectURL(redirectURI, scope, state string) (string, error) {
 	//url encode
@@ -73,7 +79,7 @@ type ResAccessToken struct {
 func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err error) {
 	urlStr := fmt.Sprintf(accessTokenURL, oauth.AppID, oauth.AppSecret, code)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPClient().HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -82,7 +88,7 @@ func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserAccessToken error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetUserAccessToken", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -92,7 +98,7 @@ func (oauth *Oauth) GetUserAccessToken(code string) (result ResAccessToken, err
 func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessToken, err error) {
 	urlStr := fmt.Sprintf(refreshAccessTokenURL, oauth.AppID, refreshToken)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPClient().HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -101,7 +107,7 @@ func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessTok
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserAccessToken error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetUserAccessToken", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -111,7 +117,7 @@ func (oauth *Oauth) RefreshAccessToken(refreshToken string) (result ResAccessTok
 func (oauth *Oauth) CheckAccessToken(accessToken, openID string) (b bool, err error) {
 	urlStr := fmt.Sprintf(checkAccessTokenURL, accessToken, openID)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPClient().HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -147,7 +153,7 @@ type UserInfo struct {
 func (oauth *Oauth) GetUserInfo(accessToken, openID string) (result UserInfo, err error) {
 	urlStr := fmt.Sprintf(userInfoURL, accessToken, openID)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPClient().HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -156,7 +162,7 @@ func (oauth *Oauth) GetUserInfo(accessToken, openID string) (result UserInfo, er
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetUserInfo error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetUserInfo", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
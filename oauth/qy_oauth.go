@@ -40,7 +40,7 @@ func (oauth *Oauth) GetQyUserInfoByCode(code string) (result QyUserInfo, err err
 	}
 	urlStr := fmt.Sprintf(qyUserInfoURL, qyAccessToken, code)
 	var response []byte
-	response, err = util.HTTPGet(urlStr)
+	response, err = oauth.HTTPClient().HTTPGet(urlStr)
 	if err != nil {
 		return
 	}
@@ -49,7 +49,7 @@ func (oauth *Oauth) GetQyUserInfoByCode(code string) (result QyUserInfo, err err
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetQyUserInfoByCode error : errcode=%v , errmsg=%v", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetQyUserInfoByCode", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
@@ -77,7 +77,7 @@ func (oauth *Oauth) GetQyUserDetailUserTicket(userTicket string) (result QyUserD
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", qyUserDetailURL, qyAccessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]string{
+	response, err = oauth.HTTPClient().PostJSON(uri, map[string]string{
 		"user_ticket": userTicket,
 	})
 	if err != nil {
@@ -88,7 +88,7 @@ func (oauth *Oauth) GetQyUserDetailUserTicket(userTicket string) (result QyUserD
 		return
 	}
 	if result.ErrCode != 0 {
-		err = fmt.Errorf("GetQyUserDetailUserTicket Error , errcode=%d , errmsg=%s", result.ErrCode, result.ErrMsg)
+		err = util.NewAPIError("GetQyUserDetailUserTicket", result.ErrCode, result.ErrMsg)
 		return
 	}
 	return
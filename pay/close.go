@@ -54,7 +54,7 @@ func (pcf *Pay) CloseOrder(c *CloseOrderParams) (rsp CloseResponse, err error) {
 		},
 		OutTradeNo: c.OutTradeNo,
 	}
-	rawRet, err := util.PostXML(closeGateway, request)
+	rawRet, err := pcf.HTTPClient().PostXML(closeGateway, request)
 	if err != nil {
 		return
 	}
@@ -62,15 +62,11 @@ func (pcf *Pay) CloseOrder(c *CloseOrderParams) (rsp CloseResponse, err error) {
 	if err != nil {
 		return
 	}
-	if rsp.ReturnCode == "SUCCESS" {
-		if rsp.ResultCode == "SUCCESS" {
-			err = nil
-			return
-		}
-		err = fmt.Errorf("refund error, errcode=%s,errmsg=%s", rsp.ErrCode, rsp.ErrCodeDes)
+	if rsp.ReturnCode == "" {
+		err = fmt.Errorf("[msg : xmlUnmarshalError] [rawReturn : %s] [params : %s] [sign : %s]",
+			string(rawRet), str, sign)
 		return
 	}
-	err = fmt.Errorf("[msg : xmlUnmarshalError] [rawReturn : %s] [params : %s] [sign : %s]",
-		string(rawRet), str, sign)
+	err = rsp.Err("CloseOrder")
 	return
 }
@@ -0,0 +1,122 @@
+package pay
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	//ErrCodeSystemError 系统错误, 需要使用相同的参数重新调用
+	ErrCodeSystemError = "SYSTEMERROR"
+	//ErrCodeOrderPaid 订单已支付
+	ErrCodeOrderPaid = "ORDERPAID"
+	//ErrCodeOrderClosed 订单已关闭
+	ErrCodeOrderClosed = "ORDERCLOSED"
+	//ErrCodeOrderNotExist 订单不存在
+	ErrCodeOrderNotExist = "ORDERNOTEXIST"
+	//ErrCodeNotEnough 商户账户余额不足
+	ErrCodeNotEnough = "NOTENOUGH"
+	//ErrCodeFrequencyLimited 接口调用频率超过限制
+	ErrCodeFrequencyLimited = "FREQUENCY_LIMITED"
+)
+
+//errCodeDescriptions 常见的错误码说明
+var errCodeDescriptions = map[string]string{
+	"SYSTEMERROR":           "系统错误，请使用相同的参数再次调用",
+	"BIZERR_NEED_RETRY":     "退款业务流程错误，需要商户触发重试",
+	"NOAUTH":                "商户无此接口权限",
+	"NOTENOUGH":             "余额不足",
+	"ORDERPAID":             "商户订单已支付",
+	"ORDERCLOSED":           "订单已关闭",
+	"ORDERNOTEXIST":         "此交易订单号不存在",
+	"APPID_NOT_EXIST":       "APPID不存在",
+	"MCHID_NOT_EXIST":       "MCHID不存在",
+	"APPID_MCHID_NOT_MATCH": "appid和mch_id不匹配",
+	"LACK_PARAMS":           "缺少参数",
+	"OUT_TRADE_NO_USED":     "商户订单号重复",
+	"SIGNERROR":             "签名错误",
+	"XML_FORMAT_ERROR":      "XML格式错误",
+	"REQUIRE_POST_METHOD":   "请使用post方法",
+	"POST_DATA_EMPTY":       "post数据为空",
+	"NOT_UTF8":              "编码格式错误",
+	"PARAM_ERROR":           "参数错误",
+	"INVALID_REQUEST":       "参数错误，请求参数未按指引进行填写",
+	"INVALID_TRANSACTIONID": "无效transaction_id",
+	"TRADE_STATE_ERROR":     "订单状态错误",
+	"USER_ACCOUNT_ABNORMAL": "退款请求失败，用户帐号注销",
+	"FREQUENCY_LIMITED":     "频率限制",
+	"ERROR":                 "业务错误",
+}
+
+// ErrCodeDescription 返回 err_code 的说明, 没有收录的 err_code 返回空字符串
+func ErrCodeDescription(errCode string) string {
+	return errCodeDescriptions[errCode]
+}
+
+// APIError 微信支付接口返回的错误, 可以通过 errors.As 获取
+// return_code 为 FAIL 时为通信错误(签名错误、参数格式错误等), 只有 ReturnMsg
+// result_code 为 FAIL 时为业务错误, ErrCode 为 ORDERPAID、SYSTEMERROR 等
+type APIError struct {
+	API        string //调用的接口, 例如 UnifiedOrder
+	ReturnCode string
+	ReturnMsg  string
+	ResultCode string
+	ErrCode    string
+	ErrCodeDes string
+}
+
+func (e *APIError) Error() string {
+	if e.ReturnCode != "SUCCESS" {
+		return fmt.Sprintf("%s error, return_code=%s, return_msg=%s", e.API, e.ReturnCode, e.ReturnMsg)
+	}
+	return fmt.Sprintf("%s error, errcode=%s, errmsg=%s", e.API, e.ErrCode, e.ErrCodeDes)
+}
+
+// Description 返回 err_code 的说明, 没有收录的 err_code 返回空字符串
+func (e *APIError) Description() string {
+	return ErrCodeDescription(e.ErrCode)
+}
+
+// Err 返回 return_code 或 result_code 不为 SUCCESS 时的 *APIError, 都为 SUCCESS 时返回 nil
+func (r *CommonResponse) Err(api string) error {
+	if r.ReturnCode == "SUCCESS" && r.ResultCode == "SUCCESS" {
+		return nil
+	}
+	return &APIError{
+		API:        api,
+		ReturnCode: r.ReturnCode,
+		ReturnMsg:  r.ReturnMsg,
+		ResultCode: r.ResultCode,
+		ErrCode:    r.ErrCode,
+		ErrCodeDes: r.ErrCodeDes,
+	}
+}
+
+// ErrCodeOf 返回 err 中的 err_code, err 不是 APIError 或者为通信错误时返回空字符串
+func ErrCodeOf(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrCode
+	}
+	return ""
+}
+
+// IsSystemError 是否为微信支付系统错误, 可以使用相同的参数重试
+func IsSystemError(err error) bool {
+	return ErrCodeOf(err) == ErrCodeSystemError
+}
+
+// IsOrderPaid 是否为订单已支付导致的错误
+func IsOrderPaid(err error) bool {
+	return ErrCodeOf(err) == ErrCodeOrderPaid
+}
+
+// IsOrderClosed 是否为订单已关闭导致的错误
+func IsOrderClosed(err error) bool {
+	return ErrCodeOf(err) == ErrCodeOrderClosed
+}
+
+// IsOrderNotExist 是否为订单不存在导致的错误
+func IsOrderNotExist(err error) bool {
+	return ErrCodeOf(err) == ErrCodeOrderNotExist
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/antsbean/wechat/util"
 	"github.com/fatih/structs"
 	"github.com/spf13/cast"
-	"log"
 	"reflect"
 	"sort"
 	"strings"
@@ -90,7 +89,8 @@ func (pcf *Pay) VerifySign(notifyRes NotifyResult) bool {
 	// STEP4, 进行MD5签名并且将所有字符转为大写.
 	sign := util.MD5Sum(signStrings)
 	if sign != *notifyRes.Sign {
-		log.Printf("sign strings %s and new sign %s", signStrings, sign)
+		//signStrings 中包含商户的 PayKey, 不能输出到日志
+		pcf.Logger().Warnf("notify sign mismatch, out_trade_no=%s", cast.ToString(notifyRes.OutTradeNo))
 		return false
 	}
 	return true
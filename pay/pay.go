@@ -2,6 +2,7 @@ package pay
 
 import (
 	"bytes"
+	gocontext "context"
 	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha256"
@@ -97,6 +98,11 @@ func NewPay(ctx *context.Context) *Pay {
 	return &pay
 }
 
+// WithContext 返回使用 ctx 发起请求的 Pay，ctx 取消或超时后请求会被中止
+func (pcf *Pay) WithContext(ctx gocontext.Context) *Pay {
+	return NewPay(pcf.Context.WithContext(ctx))
+}
+
 // BridgeConfig get js bridge config
 func (pcf *Pay) BridgeConfig(p *Params) (cfg Config, err error) {
 	var (
@@ -194,7 +200,7 @@ func (pcf *Pay) PrePayOrder(p *Params) (payOrder PreOrder, err error) {
 		Attach:         p.Attach,
 		GoodsTag:       p.GoodsTag,
 	}
-	rawRet, err := util.PostXML(payGateway, request)
+	rawRet, err := pcf.HTTPClient().PostXML(payGateway, request)
 	if err != nil {
 		return
 	}
@@ -202,16 +208,11 @@ func (pcf *Pay) PrePayOrder(p *Params) (payOrder PreOrder, err error) {
 	if err != nil {
 		return
 	}
-	if payOrder.ReturnCode == "SUCCESS" {
-		// pay success
-		if payOrder.ResultCode == "SUCCESS" {
-			err = nil
-			return
-		}
-		err = errors.New(payOrder.ErrCode + payOrder.ErrCodeDes)
+	if payOrder.ReturnCode == "" {
+		err = errors.New("[msg : xmlUnmarshalError] [rawReturn : " + string(rawRet) + "] [params : " + str + "] [sign : " + sign + "]")
 		return
 	}
-	err = errors.New("[msg : xmlUnmarshalError] [rawReturn : " + string(rawRet) + "] [params : " + str + "] [sign : " + sign + "]")
+	err = payOrder.Err("UnifiedOrder")
 	return
 }
 
@@ -102,7 +102,7 @@ func (pcf *Pay) QueryOrder(q *QueryOrderParams) (rsp QueryResponse, err error) {
 		OutTradeNo:    q.OutTradeNo,
 		TransactionID: q.TransactionID,
 	}
-	rawRet, err := util.PostXML(queryGateway, request)
+	rawRet, err := pcf.HTTPClient().PostXML(queryGateway, request)
 	if err != nil {
 		return
 	}
@@ -110,15 +110,11 @@ func (pcf *Pay) QueryOrder(q *QueryOrderParams) (rsp QueryResponse, err error) {
 	if err != nil {
 		return
 	}
-	if rsp.ReturnCode == "SUCCESS" {
-		if rsp.ResultCode == "SUCCESS" {
-			err = nil
-			return
-		}
-		err = fmt.Errorf("refund error, errcode=%s,errmsg=%s", rsp.ErrCode, rsp.ErrCodeDes)
+	if rsp.ReturnCode == "" {
+		err = fmt.Errorf("[msg : xmlUnmarshalError] [rawReturn : %s] [params : %s] [sign : %s]",
+			string(rawRet), str, sign)
 		return
 	}
-	err = fmt.Errorf("[msg : xmlUnmarshalError] [rawReturn : %s] [params : %s] [sign : %s]",
-		string(rawRet), str, sign)
+	err = rsp.Err("QueryOrder")
 	return
 }
@@ -79,7 +79,7 @@ func (pcf *Pay) Refund(p *RefundParams) (rsp RefundResponse, err error) {
 		RefundFee:     p.RefundFee,
 		RefundDesc:    p.RefundDesc,
 	}
-	rawRet, err := util.PostXMLWithTLS(refundGateway, request, p.RootCa, pcf.PayMchID)
+	rawRet, err := pcf.HTTPClient().PostXMLWithTLS(refundGateway, request, p.RootCa, pcf.PayMchID)
 	if err != nil {
 		return
 	}
@@ -87,15 +87,11 @@ func (pcf *Pay) Refund(p *RefundParams) (rsp RefundResponse, err error) {
 	if err != nil {
 		return
 	}
-	if rsp.ReturnCode == "SUCCESS" {
-		if rsp.ResultCode == "SUCCESS" {
-			err = nil
-			return
-		}
-		err = fmt.Errorf("refund error, errcode=%s,errmsg=%s", rsp.ErrCode, rsp.ErrCodeDes)
+	if rsp.ReturnCode == "" {
+		err = fmt.Errorf("[msg : xmlUnmarshalError] [rawReturn : %s] [params : %s] [sign : %s]",
+			string(rawRet), str, sign)
 		return
 	}
-	err = fmt.Errorf("[msg : xmlUnmarshalError] [rawReturn : %s] [params : %s] [sign : %s]",
-		string(rawRet), str, sign)
+	err = rsp.Err("Refund")
 	return
 }
@@ -1,6 +1,7 @@
 package qr
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -35,6 +36,11 @@ func NewQR(context *context.Context) *QR {
 	return q
 }
 
+// WithContext 返回使用 ctx 发起请求的 QR，ctx 取消或超时后请求会被中止
+func (q *QR) WithContext(ctx gocontext.Context) *QR {
+	return NewQR(q.Context.WithContext(ctx))
+}
+
 // Request 临时二维码
 type Request struct {
 	ExpireSeconds int64  `json:"expire_seconds,omitempty"`
@@ -63,7 +69,7 @@ func (q *QR) GetQRTicket(tq *Request) (t *Ticket, err error) {
 	}
 
 	uri := fmt.Sprintf(qrCreateURL, accessToken)
-	response, err := util.PostJSON(uri, tq)
+	response, err := q.HTTPClient().PostJSON(uri, tq)
 	if err != nil {
 		err = fmt.Errorf("get qr ticket failed, %s", err)
 		return
@@ -74,7 +80,7 @@ func (q *QR) GetQRTicket(tq *Request) (t *Ticket, err error) {
 	if err != nil {
 		return
 	}
-
+	err = t.CommonError.Err("GetQRTicket")
 	return
 }
 
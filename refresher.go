@@ -0,0 +1,293 @@
+package wechat
+
+import (
+	gocontext "context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antsbean/wechat/context"
+	"github.com/antsbean/wechat/js"
+)
+
+const (
+	//RefreshAccessToken 刷新 access_token 的任务名称
+	RefreshAccessToken = "access_token"
+	//RefreshJsAPITicket 刷新 jsapi_ticket 的任务名称
+	RefreshJsAPITicket = "jsapi_ticket"
+	//RefreshWxCardTicket 刷新卡券 api_ticket 的任务名称
+	RefreshWxCardTicket = "wx_card_ticket"
+	//RefreshComponentAccessToken 刷新 component_access_token 的任务名称
+	RefreshComponentAccessToken = "component_access_token"
+	//RefreshAuthrAccessToken 刷新授权方 access_token 的任务名称
+	RefreshAuthrAccessToken = "authorizer_access_token"
+)
+
+// RefresherOpts 后台刷新凭证的配置
+type RefresherOpts struct {
+	AccessToken          bool //刷新 access_token
+	JsAPITicket          bool //刷新 jsapi_ticket
+	WxCardTicket         bool //刷新卡券 api_ticket
+	ComponentAccessToken bool //刷新第三方平台的 component_access_token, 使用最近一次推送的 component_verify_ticket
+
+	//AuthorizerRefreshTokens 返回需要刷新 access_token 的授权方 appid 及对应的 authorizer_refresh_token
+	AuthorizerRefreshTokens func() (map[string]string, error)
+
+	Ahead      time.Duration //在缓存过期之前多久刷新, 默认 5 分钟
+	Jitter     time.Duration //随机提前刷新的最大时间, 避免多个进程同时刷新, 默认 30 秒
+	MinBackoff time.Duration //刷新失败后第一次重试的间隔, 之后每次翻倍, 默认 1 秒
+	MaxBackoff time.Duration //刷新失败后重试的最大间隔, 默认 5 分钟
+
+	//OnRefresh 刷新成功后调用
+	OnRefresh func(name string)
+	//OnError 刷新失败后调用
+	OnError func(name string, err error)
+}
+
+// AuthrRefreshErrors 刷新授权方 access_token 失败的授权方 appid 及对应的错误, 通过 RefresherOpts.OnError 返回
+type AuthrRefreshErrors map[string]error
+
+// Error 实现 error 接口, 按 appid 排序输出
+func (errs AuthrRefreshErrors) Error() string {
+	appids := make([]string, 0, len(errs))
+	for appid := range errs {
+		appids = append(appids, appid)
+	}
+	sort.Strings(appids)
+	msgs := make([]string, 0, len(appids))
+	for _, appid := range appids {
+		msgs = append(msgs, fmt.Sprintf("appid=%s: %v", appid, errs[appid]))
+	}
+	return "refresh authorizer access_token failed: " + strings.Join(msgs, "; ")
+}
+
+// Refresher 在凭证过期之前于后台主动刷新，避免请求时才去获取
+type Refresher struct {
+	ctx    *context.Context
+	opts   RefresherOpts
+	cancel gocontext.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// refreshTask 一个需要定期刷新的凭证
+type refreshTask struct {
+	name string
+	//due 返回下一次需要刷新的时间
+	due func() time.Time
+	//refresh 执行刷新
+	refresh func() error
+}
+
+// StartRefresher 启动后台刷新凭证的 goroutine, 通过 Refresher.Stop 停止
+func (wc *Wechat) StartRefresher(opts RefresherOpts) *Refresher {
+	if opts.Ahead <= 0 {
+		opts.Ahead = 5 * time.Minute
+	}
+	if opts.Jitter <= 0 {
+		opts.Jitter = 30 * time.Second
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 5 * time.Minute
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = opts.MinBackoff
+	}
+
+	goCtx, cancel := gocontext.WithCancel(gocontext.Background())
+	r := &Refresher{
+		ctx:    wc.Context.WithContext(goCtx),
+		opts:   opts,
+		cancel: cancel,
+	}
+	for _, task := range r.tasks() {
+		r.wg.Add(1)
+		go r.run(task)
+	}
+	return r
+}
+
+// Stop 停止后台刷新, 并等待正在进行的刷新结束
+func (r *Refresher) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Refresher) tasks() (tasks []*refreshTask) {
+	ctx := r.ctx
+	if r.opts.AccessToken {
+		tasks = append(tasks, &refreshTask{
+			name: RefreshAccessToken,
+			due:  r.dueOf(ctx.AccessTokenCacheKey()),
+			refresh: func() error {
+				_, err := ctx.ForceRefreshAccessToken()
+				return err
+			},
+		})
+	}
+	ticketTasks := []struct {
+		enabled    bool
+		name       string
+		ticketType js.TicketType
+	}{
+		{r.opts.JsAPITicket, RefreshJsAPITicket, js.TicketTypeJsAPI},
+		{r.opts.WxCardTicket, RefreshWxCardTicket, js.TicketTypeWxCard},
+	}
+	for _, t := range ticketTasks {
+		if !t.enabled {
+			continue
+		}
+		ticketType := t.ticketType
+		jsapi := js.NewJs(ctx)
+		tasks = append(tasks, &refreshTask{
+			name: t.name,
+			due:  r.dueOf(jsapi.TicketCacheKey(ticketType)),
+			refresh: func() error {
+				_, err := jsapi.RefreshTicket(ticketType)
+				return err
+			},
+		})
+	}
+	if r.opts.ComponentAccessToken {
+		tasks = append(tasks, &refreshTask{
+			name: RefreshComponentAccessToken,
+			due:  r.dueOf(ctx.ComponentAccessTokenCacheKey()),
+			refresh: func() error {
+				verifyTicket, err := ctx.GetComponentVerifyTicket()
+				if err != nil {
+					return err
+				}
+				_, err = ctx.SetComponentAccessToken(verifyTicket)
+				return err
+			},
+		})
+	}
+	if r.opts.AuthorizerRefreshTokens != nil {
+		tasks = append(tasks, r.authrTask())
+	}
+	return
+}
+
+// authrTask 刷新所有授权方的 access_token, 每次只刷新即将过期的授权方
+func (r *Refresher) authrTask() *refreshTask {
+	ctx := r.ctx
+	return &refreshTask{
+		name: RefreshAuthrAccessToken,
+		due: func() time.Time {
+			authorizers, err := r.opts.AuthorizerRefreshTokens()
+			if err != nil {
+				return time.Now()
+			}
+			var due time.Time
+			for appid := range authorizers {
+				d := r.dueOf(context.AuthrAccessTokenCacheKey(appid))()
+				if due.IsZero() || d.Before(due) {
+					due = d
+				}
+			}
+			if due.IsZero() {
+				//还没有授权方, 稍后再检查
+				due = time.Now().Add(r.opts.Ahead)
+			}
+			return due
+		},
+		refresh: func() error {
+			authorizers, err := r.opts.AuthorizerRefreshTokens()
+			if err != nil {
+				return err
+			}
+			//一个授权方刷新失败时继续刷新其它授权方
+			errs := AuthrRefreshErrors{}
+			for appid, refreshToken := range authorizers {
+				if r.dueOf(context.AuthrAccessTokenCacheKey(appid))().After(time.Now()) {
+					continue
+				}
+				if _, err := ctx.RefreshAuthrToken(appid, refreshToken); err != nil {
+					errs[appid] = err
+				}
+			}
+			if len(errs) > 0 {
+				return errs
+			}
+			return nil
+		},
+	}
+}
+
+// dueOf 根据缓存的过期时间计算下次刷新的时间, 缓存不存在时立即刷新
+func (r *Refresher) dueOf(cacheKey string) func() time.Time {
+	return func() time.Time {
+		expiresAt, ok := r.ctx.CredentialExpiresAt(cacheKey)
+		if !ok {
+			return time.Now()
+		}
+		return expiresAt.Add(-r.opts.Ahead)
+	}
+}
+
+func (r *Refresher) run(task *refreshTask) {
+	defer r.wg.Done()
+
+	var backoff time.Duration
+	refreshed := false
+	for {
+		wait := backoff
+		if wait == 0 {
+			wait = time.Until(task.due()) - time.Duration(rand.Int63n(int64(r.opts.Jitter)))
+			if refreshed && wait <= 0 {
+				//刚刷新过但缓存中没有过期时间, 避免不停地刷新
+				wait = r.opts.Ahead
+			}
+		}
+		if !r.sleep(wait) {
+			return
+		}
+
+		if err := task.refresh(); err != nil {
+			if r.ctx.GoContext().Err() != nil {
+				return
+			}
+			refreshed = false
+			if r.opts.OnError != nil {
+				r.opts.OnError(task.name, err)
+			}
+			if backoff == 0 {
+				backoff = r.opts.MinBackoff
+			} else if backoff *= 2; backoff > r.opts.MaxBackoff {
+				backoff = r.opts.MaxBackoff
+			}
+			continue
+		}
+		backoff = 0
+		refreshed = true
+		if r.opts.OnRefresh != nil {
+			r.opts.OnRefresh(task.name)
+		}
+	}
+}
+
+// sleep 等待 d, Refresher 被停止时返回 false
+func (r *Refresher) sleep(d time.Duration) bool {
+	done := r.ctx.GoContext().Done()
+	if d <= 0 {
+		select {
+		case <-done:
+			return false
+		default:
+			return true
+		}
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
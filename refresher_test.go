@@ -0,0 +1,157 @@
+package wechat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/util"
+)
+
+func newTestWechat(handler http.HandlerFunc) (*Wechat, func()) {
+	ts := httptest.NewServer(handler)
+	wc := NewWechat(&Config{
+		AppID:     "appid",
+		AppSecret: "secret",
+		Cache:     cache.NewMemory(),
+		Endpoints: util.Endpoints{APIBaseURL: ts.URL},
+	})
+	return wc, ts.Close
+}
+
+func TestRefresher(t *testing.T) {
+	var tokenRequests int32
+	wc, closeServer := newTestWechat(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+		//缓存时间为 expires_in - 1500, 即 2 秒
+		fmt.Fprintf(w, `{"access_token":"token%d","expires_in":1502}`, n)
+	})
+	defer closeServer()
+
+	refreshed := make(chan string, 10)
+	refresher := wc.StartRefresher(RefresherOpts{
+		AccessToken: true,
+		Ahead:       time.Second,
+		Jitter:      time.Millisecond,
+		OnRefresh: func(name string) {
+			refreshed <- name
+		},
+	})
+	for i := 0; i < 2; i++ {
+		select {
+		case name := <-refreshed:
+			if name != RefreshAccessToken {
+				t.Errorf("unexpected task %s", name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("access_token not refreshed in time")
+		}
+	}
+	refresher.Stop()
+
+	n := atomic.LoadInt32(&tokenRequests)
+	if n != 2 {
+		t.Errorf("expect access_token refreshed 2 times, got %d", n)
+	}
+	if token, _ := wc.GetAccessToken(); token != fmt.Sprintf("token%d", n) {
+		t.Errorf("expect latest access_token, got %s", token)
+	}
+}
+
+func TestRefresherOnError(t *testing.T) {
+	wc, closeServer := newTestWechat(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errcode":40013,"errmsg":"invalid appid"}`)
+	})
+	defer closeServer()
+
+	errs := make(chan error, 10)
+	refresher := wc.StartRefresher(RefresherOpts{
+		JsAPITicket: true,
+		MinBackoff:  10 * time.Millisecond,
+		OnError: func(name string, err error) {
+			if name != RefreshJsAPITicket {
+				t.Errorf("unexpected task %s", name)
+			}
+			errs <- err
+		},
+	})
+	defer refresher.Stop()
+
+	//失败后会退避重试
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("expect error")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("OnError not called in time")
+		}
+	}
+}
+
+func TestRefresherAuthorizerErrors(t *testing.T) {
+	wc, closeServer := newTestWechat(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		appid := req["authorizer_appid"]
+		if appid == "bad" {
+			fmt.Fprint(w, `{"errcode":61003,"errmsg":"component is not authorized by this account"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"authorizer_access_token":"token_%s","expires_in":7200}`, appid)
+	})
+	defer closeServer()
+	wc.Context.Cache.Set(wc.Context.ComponentAccessTokenCacheKey(), "component_token", time.Hour)
+
+	errs := make(chan error, 10)
+	refresher := wc.StartRefresher(RefresherOpts{
+		AuthorizerRefreshTokens: func() (map[string]string, error) {
+			return map[string]string{"bad": "refresh_bad", "good": "refresh_good"}, nil
+		},
+		MinBackoff: time.Minute,
+		OnError: func(name string, err error) {
+			errs <- err
+		},
+	})
+	defer refresher.Stop()
+
+	select {
+	case err := <-errs:
+		authrErrs, ok := err.(AuthrRefreshErrors)
+		if !ok || len(authrErrs) != 1 || authrErrs["bad"] == nil {
+			t.Errorf("expect error of authorizer bad only, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError not called in time")
+	}
+	//一个授权方失败不影响其它授权方
+	if token, err := wc.Context.GetAuthrAccessToken("good"); token != "token_good" || err != nil {
+		t.Errorf("expect authorizer good refreshed, got %s, err=%v", token, err)
+	}
+}
+
+func TestRefresherBackoffDefaults(t *testing.T) {
+	wc := NewWechat(&Config{AppID: "appid", AppSecret: "secret", Cache: cache.NewMemory()})
+	tests := []struct {
+		min, max         time.Duration
+		wantMin, wantMax time.Duration
+	}{
+		{0, 0, time.Second, 5 * time.Minute},
+		{time.Second, time.Minute, time.Second, time.Minute},
+		{10 * time.Minute, 0, 10 * time.Minute, 10 * time.Minute},
+		{10 * time.Minute, time.Minute, 10 * time.Minute, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		r := wc.StartRefresher(RefresherOpts{MinBackoff: tt.min, MaxBackoff: tt.max})
+		r.Stop()
+		if r.opts.MinBackoff != tt.wantMin || r.opts.MaxBackoff != tt.wantMax {
+			t.Errorf("MinBackoff=%v MaxBackoff=%v: got %v %v, want %v %v", tt.min, tt.max, r.opts.MinBackoff, r.opts.MaxBackoff, tt.wantMin, tt.wantMax)
+		}
+	}
+}
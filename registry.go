@@ -0,0 +1,220 @@
+package wechat
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/server"
+	"github.com/antsbean/wechat/util"
+)
+
+// ErrAccountNotFound 没有找到消息推送对应的账号
+var ErrAccountNotFound = errors.New("wechat: account not found")
+
+// ErrBodyTooLarge 消息推送的 body 超过了 RegistryConfig.MaxBodySize
+var ErrBodyTooLarge = errors.New("wechat: request body too large")
+
+// DefaultMaxBodySize 消息推送 body 默认的最大字节数, 微信的推送一般只有几 KB
+const DefaultMaxBodySize = 1 << 20
+
+// RegistryConfig 多个账号共享的配置
+type RegistryConfig struct {
+	Cache      cache.Cache
+	HTTPClient util.Doer
+	Locker     cache.Locker
+
+	Logger util.Logger
+	Hooks  util.Hooks
+
+	//MaxBodySize 消息推送 body 的最大字节数, 为 0 时使用 DefaultMaxBodySize
+	MaxBodySize int64
+}
+
+// Registry 在一个进程中管理多个公众号/小程序, 账号之间共享缓存以及 http client
+// 可以并发地注册、查找账号
+type Registry struct {
+	cfg RegistryConfig
+
+	mu        sync.RWMutex
+	accounts  map[string]*Wechat //AppID -> Wechat
+	userNames map[string]string  //原始ID -> AppID
+
+	serverHandler func(wc *Wechat, srv *server.Server)
+}
+
+// NewRegistry init
+func NewRegistry(cfg *RegistryConfig) *Registry {
+	r := &Registry{
+		accounts:  make(map[string]*Wechat),
+		userNames: make(map[string]string),
+	}
+	if cfg != nil {
+		r.cfg = *cfg
+	}
+	if r.cfg.MaxBodySize <= 0 {
+		r.cfg.MaxBodySize = DefaultMaxBodySize
+	}
+	return r
+}
+
+// Register 注册账号, 已经存在相同 AppID 的账号时替换
+// cfg 中没有设置的 Cache、HTTPClient、Locker、Logger、Hooks 使用 Registry 共享的配置
+func (r *Registry) Register(cfg *Config) *Wechat {
+	c := *cfg
+	if c.Cache == nil {
+		c.Cache = r.cfg.Cache
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = r.cfg.HTTPClient
+	}
+	if c.Locker == nil {
+		c.Locker = r.cfg.Locker
+	}
+	if c.Logger == nil {
+		c.Logger = r.cfg.Logger
+	}
+	if c.Hooks.Before == nil && c.Hooks.After == nil {
+		c.Hooks = r.cfg.Hooks
+	}
+	wc := NewWechat(&c)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remove(c.AppID)
+	r.accounts[c.AppID] = wc
+	if c.UserName != "" {
+		r.userNames[c.UserName] = c.AppID
+	}
+	return wc
+}
+
+// Unregister 移除账号
+func (r *Registry) Unregister(appID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remove(appID)
+}
+
+func (r *Registry) remove(appID string) {
+	delete(r.accounts, appID)
+	for userName, id := range r.userNames {
+		if id == appID {
+			delete(r.userNames, userName)
+		}
+	}
+}
+
+// Get 根据 AppID 获取账号
+func (r *Registry) Get(appID string) (*Wechat, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	wc, ok := r.accounts[appID]
+	return wc, ok
+}
+
+// GetByUserName 根据原始ID获取账号
+func (r *Registry) GetByUserName(userName string) (*Wechat, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	wc, ok := r.accounts[r.userNames[userName]]
+	return wc, ok
+}
+
+// AppIDs 返回所有已注册账号的 AppID
+func (r *Registry) AppIDs() []string {
+	r.mu.RLock()
+	appIDs := make([]string, 0, len(r.accounts))
+	for appID := range r.accounts {
+		appIDs = append(appIDs, appID)
+	}
+	r.mu.RUnlock()
+	sort.Strings(appIDs)
+	return appIDs
+}
+
+// Match 查找消息推送对应的账号
+// 先以 url path 的最后一段作为 AppID 查找(如 /wechat/callback/{appid}), 找不到时再根据消息中的 ToUserName 查找
+// body 超过 RegistryConfig.MaxBodySize 时返回 ErrBodyTooLarge
+func (r *Registry) Match(req *http.Request) (*Wechat, error) {
+	if wc, ok := r.Get(path.Base(req.URL.Path)); ok {
+		return wc, nil
+	}
+	if req.Body == nil {
+		return nil, ErrAccountNotFound
+	}
+
+	//没有校验签名之前只读取 MaxBodySize 以内的 body
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, r.cfg.MaxBodySize+1))
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > r.cfg.MaxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+	//还原 body 供 Server 解析
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	//明文消息和加密消息的外层都有 ToUserName
+	var msg struct {
+		ToUserName string `xml:"ToUserName" json:"ToUserName"`
+	}
+	unmarshal := xml.Unmarshal
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		unmarshal = json.Unmarshal
+	}
+	if err := unmarshal(body, &msg); err != nil || msg.ToUserName == "" {
+		return nil, ErrAccountNotFound
+	}
+	if wc, ok := r.GetByUserName(msg.ToUserName); ok {
+		return wc, nil
+	}
+	return nil, ErrAccountNotFound
+}
+
+// SetServerHandler 设置处理消息推送的方法, 一般在其中调用 srv.SetMessageHandler
+func (r *Registry) SetServerHandler(handler func(wc *Wechat, srv *server.Server)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.serverHandler = handler
+}
+
+// ServeHTTP 将消息推送交给对应账号的 Server 处理, body 超过 RegistryConfig.MaxBodySize 时返回 413
+func (r *Registry) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	wc, err := r.Match(req)
+	if err == ErrBodyTooLarge {
+		http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+		return
+	}
+	if req.Body != nil {
+		//根据 url path 找到账号时由 Server 读取 body, 同样需要限制大小
+		req.Body = http.MaxBytesReader(writer, req.Body, r.cfg.MaxBodySize)
+	}
+
+	wc = wc.WithContext(req.Context())
+	srv := wc.GetServer(req, writer)
+	r.mu.RLock()
+	handler := r.serverHandler
+	r.mu.RUnlock()
+	if handler != nil {
+		handler(wc, srv)
+	}
+	if err := srv.Serve(); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	srv.Send()
+}
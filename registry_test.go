@@ -0,0 +1,151 @@
+package wechat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/message"
+	"github.com/antsbean/wechat/server"
+	"github.com/antsbean/wechat/util"
+)
+
+func newTestRegistry() *Registry {
+	registry := NewRegistry(nil)
+	for i := 1; i <= 2; i++ {
+		registry.Register(&Config{
+			AppID:    fmt.Sprintf("appid%d", i),
+			UserName: fmt.Sprintf("gh_%d", i),
+			Token:    fmt.Sprintf("token%d", i),
+		})
+	}
+	registry.SetServerHandler(func(wc *Wechat, srv *server.Server) {
+		srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+			return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(wc.Context.AppID)}
+		})
+	})
+	return registry
+}
+
+func newTestCallback(path, token, toUserName string) *http.Request {
+	timestamp, nonce := "1500000000", "nonce"
+	body := fmt.Sprintf(`<xml><ToUserName>%s</ToUserName><FromUserName>user</FromUserName><CreateTime>1500000000</CreateTime><MsgType>text</MsgType><Content>hi</Content></xml>`, toUserName)
+	target := fmt.Sprintf("%s?timestamp=%s&nonce=%s&signature=%s", path, timestamp, nonce, util.Signature(token, timestamp, nonce))
+	return httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+}
+
+func TestRegistry(t *testing.T) {
+	registry := newTestRegistry()
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		appID  string
+	}{
+		{"path", newTestCallback("/callback/appid1", "token1", "gh_2"), http.StatusOK, "appid1"},
+		{"ToUserName", newTestCallback("/callback", "token2", "gh_2"), http.StatusOK, "appid2"},
+		{"unknown", newTestCallback("/callback", "token1", "gh_3"), http.StatusNotFound, ""},
+		{"bad signature", newTestCallback("/callback/appid2", "token1", "gh_2"), http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		registry.ServeHTTP(rec, tt.req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expect status %d, got %d", tt.name, tt.status, rec.Code)
+			continue
+		}
+		if tt.appID != "" && !strings.Contains(rec.Body.String(), "<Content><![CDATA["+tt.appID+"]]></Content>") {
+			t.Errorf("%s: expect reply from %s, got %s", tt.name, tt.appID, rec.Body.String())
+		}
+	}
+
+	registry.Unregister("appid2")
+	if _, ok := registry.GetByUserName("gh_2"); ok {
+		t.Error("expect gh_2 unregistered")
+	}
+	if appIDs := registry.AppIDs(); len(appIDs) != 1 || appIDs[0] != "appid1" {
+		t.Errorf("unexpected AppIDs %v", appIDs)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	registry := newTestRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			appID := fmt.Sprintf("appid%d", i%2+1)
+			if i%5 == 0 {
+				registry.Register(&Config{AppID: appID, UserName: fmt.Sprintf("gh_%d", i%2+1), Token: fmt.Sprintf("token%d", i%2+1)})
+			}
+			rec := httptest.NewRecorder()
+			registry.ServeHTTP(rec, newTestCallback("/callback", fmt.Sprintf("token%d", i%2+1), fmt.Sprintf("gh_%d", i%2+1)))
+			if !strings.Contains(rec.Body.String(), appID) {
+				t.Errorf("expect reply from %s, got %s", appID, rec.Body.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRegistrySetServerHandlerConcurrent(t *testing.T) {
+	registry := newTestRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			registry.SetServerHandler(func(wc *Wechat, srv *server.Server) {
+				srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+					return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(wc.Context.AppID)}
+				})
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			registry.ServeHTTP(rec, newTestCallback("/callback/appid1", "token1", "gh_1"))
+			if rec.Code != http.StatusOK {
+				t.Errorf("expect status 200, got %d", rec.Code)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRegistryMaxBodySize(t *testing.T) {
+	registry := NewRegistry(&RegistryConfig{Cache: cache.NewMemory(), MaxBodySize: 64})
+	registry.Register(&Config{AppID: "appid1", UserName: "gh_1", Token: "token1"})
+
+	large := strings.Repeat("a", 100)
+	tests := []struct {
+		path   string
+		status int
+	}{
+		//根据 ToUserName 查找账号时在 Match 中拒绝
+		{"/callback", http.StatusRequestEntityTooLarge},
+		//根据 url path 找到账号时由 Server 读取 body 失败
+		{"/callback/appid1", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := newTestCallback(tt.path, "token1", "gh_1")
+		req.Body = ioutil.NopCloser(strings.NewReader(`<xml><ToUserName>gh_1</ToUserName><Content>` + large + `</Content></xml>`))
+		rec := httptest.NewRecorder()
+		registry.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expect status %d, got %d %s", tt.path, tt.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := newTestCallback("/callback", "token1", "gh_1")
+	req.Body = ioutil.NopCloser(strings.NewReader(large + large))
+	if _, err := registry.Match(req); err != ErrBodyTooLarge {
+		t.Errorf("expect ErrBodyTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/antsbean/wechat/context"
+	"github.com/antsbean/wechat/message"
+)
+
+var (
+	//ErrAsyncQueueFull 异步处理的队列已满, 消息被丢弃
+	ErrAsyncQueueFull = errors.New("async reply queue is full")
+	//ErrAsyncReplierClosed AsyncReplier 已经关闭, 消息被丢弃
+	ErrAsyncReplierClosed = errors.New("async replier is closed")
+)
+
+// AsyncOpts 异步回复的配置
+type AsyncOpts struct {
+	Workers   int //处理消息的 goroutine 数量, 默认 10
+	QueueSize int //等待处理的消息的最大数量, 超出时丢弃消息并调用 OnError, 默认 100
+
+	//OnError 消息被丢弃、处理消息 panic 或者发送客服消息失败时调用
+	OnError func(msg message.MixMessage, err error)
+}
+
+// AsyncReplier 异步回复消息
+// 收到消息后立即回复 success, 在后台执行 messageHandler, 返回的回复通过客服消息接口发送给用户
+// 适用于处理时间可能超过 5 秒的场景
+type AsyncReplier struct {
+	opts AsyncOpts
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *asyncJob
+	wg     sync.WaitGroup
+}
+
+type asyncJob struct {
+	ctx     *context.Context
+	msg     message.MixMessage
+	handler func(message.MixMessage) *message.Reply
+}
+
+// NewAsyncReplier 创建 AsyncReplier 并启动处理消息的 goroutine, 通过 Server.SetAsyncReplier 使用
+// 多个 Server 可以共享同一个 AsyncReplier
+func NewAsyncReplier(opts AsyncOpts) *AsyncReplier {
+	if opts.Workers <= 0 {
+		opts.Workers = 10
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	r := &AsyncReplier{
+		opts:  opts,
+		queue: make(chan *asyncJob, opts.QueueSize),
+	}
+	r.wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go r.work()
+	}
+	return r
+}
+
+// Close 不再接收新的消息, 并等待队列中的消息处理完成
+func (r *AsyncReplier) Close() {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+//submit 将消息放入队列, 队列已满时返回 ErrAsyncQueueFull
+func (r *AsyncReplier) submit(job *asyncJob) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return ErrAsyncReplierClosed
+	}
+	select {
+	case r.queue <- job:
+		return nil
+	default:
+		return ErrAsyncQueueFull
+	}
+}
+
+func (r *AsyncReplier) work() {
+	defer r.wg.Done()
+	for job := range r.queue {
+		if err := r.handle(job); err != nil {
+			r.onError(job.msg, err)
+		}
+	}
+}
+
+func (r *AsyncReplier) handle(job *asyncJob) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("panic error: %v\n%s", e, debug.Stack())
+		}
+	}()
+	reply := job.handler(job.msg)
+	if reply == nil {
+		return nil
+	}
+	customerMsg, err := message.NewCustomerMessageFromReply(string(job.msg.FromUserName), reply)
+	if err != nil {
+		return err
+	}
+	return message.NewMessageManager(job.ctx).Send(customerMsg)
+}
+
+func (r *AsyncReplier) onError(msg message.MixMessage, err error) {
+	if r.opts.OnError != nil {
+		r.opts.OnError(msg, err)
+	}
+}
+
+// SetAsyncReplier 设置异步回复, 为 nil 时同步回复
+func (srv *Server) SetAsyncReplier(replier *AsyncReplier) {
+	srv.asyncReplier = replier
+}
+
+//handleAsync 将消息交给 AsyncReplier 处理
+func (srv *Server) handleAsync(msg message.MixMessage) {
+	srv.asyncAccepted = true
+	job := &asyncJob{
+		//请求结束后 ctx 可能会被取消, 发送客服消息时不再使用
+		ctx:     srv.Context.WithContext(gocontext.Background()),
+		msg:     msg,
+		handler: srv.messageHandler,
+	}
+	if err := srv.asyncReplier.submit(job); err != nil {
+		srv.asyncReplier.onError(msg, err)
+	}
+}
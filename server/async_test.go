@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/antsbean/wechat/context"
+	"github.com/antsbean/wechat/message"
+	"github.com/antsbean/wechat/util"
+)
+
+func TestServerAsync(t *testing.T) {
+	var mu sync.Mutex
+	sent := make(map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var msg message.CustomerMessage
+		json.Unmarshal(body, &msg)
+		if msg.ToUser == "bad" {
+			fmt.Fprint(w, `{"errcode":40003,"errmsg":"invalid openid"}`)
+			return
+		}
+		mu.Lock()
+		sent[msg.ToUser] = msg.Text.Content
+		mu.Unlock()
+		fmt.Fprint(w, `{"errcode":0,"errmsg":"ok"}`)
+	}))
+	defer ts.Close()
+
+	ctx := &context.Context{AppID: "appid", Token: "token"}
+	ctx.Endpoints = util.Endpoints{APIBaseURL: ts.URL}
+	ctx.SetAccessTokenLock(new(sync.RWMutex))
+	ctx.SetGetAccessTokenFunc(func(ctx *context.Context) (string, error) {
+		return "token", nil
+	})
+
+	var errMu sync.Mutex
+	var errs []error
+	replier := NewAsyncReplier(AsyncOpts{
+		Workers: 2,
+		OnError: func(msg message.MixMessage, err error) {
+			errMu.Lock()
+			errs = append(errs, err)
+			errMu.Unlock()
+		},
+	})
+	srv := NewServer(ctx)
+	srv.SetAsyncReplier(replier)
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		if msg.Content == "panic" {
+			panic("boom")
+		}
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("re:" + msg.Content)}
+	})
+
+	for i, user := range []string{"user1", "user2", "bad", "user3"} {
+		content := fmt.Sprintf("content%d", i)
+		if user == "user3" {
+			content = "panic"
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, newTextRequest("token", user, content))
+		if rec.Body.String() != "success" {
+			t.Errorf("expect success, got %s", rec.Body.String())
+		}
+	}
+	replier.Close()
+
+	if len(sent) != 2 || sent["user1"] != "re:content0" || sent["user2"] != "re:content1" {
+		t.Errorf("unexpected customer messages %v", sent)
+	}
+	if len(errs) != 2 {
+		t.Errorf("expect send error and panic reported, got %v", errs)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, newTextRequest("token", "user4", "hi"))
+	if len(errs) != 3 || errs[2] != ErrAsyncReplierClosed {
+		t.Errorf("expect ErrAsyncReplierClosed, got %v", errs)
+	}
+}
+
+func TestAsyncReplierQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	var dropped error
+	replier := NewAsyncReplier(AsyncOpts{
+		Workers:   1,
+		QueueSize: 1,
+		OnError: func(msg message.MixMessage, err error) {
+			dropped = err
+		},
+	})
+	handler := func(msg message.MixMessage) *message.Reply {
+		<-block
+		return nil
+	}
+	srv := NewServer(&context.Context{Token: "token"})
+	srv.SetAsyncReplier(replier)
+	srv.SetMessageHandler(handler)
+
+	//第一条消息被 worker 取走, 第二条放入队列, 之后的消息被丢弃
+	for i := 0; i < 3 && dropped == nil; i++ {
+		srv.ServeHTTP(httptest.NewRecorder(), newTextRequest("token", "user", strings.Repeat("a", i)))
+	}
+	for i := 0; dropped == nil && i < 10; i++ {
+		srv.ServeHTTP(httptest.NewRecorder(), newTextRequest("token", "user", "more"))
+	}
+	close(block)
+	replier.Close()
+	if dropped != ErrAsyncQueueFull {
+		t.Errorf("expect ErrAsyncQueueFull, got %v", dropped)
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/message"
+)
+
+//defaultDedupWindow 微信在 5 秒内收不到响应会重试三次, 默认在 30 秒内去重
+const defaultDedupWindow = 30 * time.Second
+
+// SetDedupWindow 设置消息去重的时间窗口, 默认 30 秒, 小于等于 0 时不去重
+// 窗口内重复推送的消息(包括微信的重试)不会再交给 messageHandler 处理, 直接回复空串
+// 去重记录保存在 Context.Cache 中, 未设置 Cache 时不去重
+func (srv *Server) SetDedupWindow(window time.Duration) {
+	srv.dedupWindow = window
+}
+
+//dedupEnabled 是否开启了消息去重
+func (srv *Server) dedupEnabled() bool {
+	return srv.dedupWindow > 0 && srv.Cache != nil
+}
+
+//isDuplicate 判断消息是否已经处理过, 没有处理过时记录下来
+func (srv *Server) isDuplicate(msg *message.MixMessage) bool {
+	if !srv.dedupEnabled() {
+		return false
+	}
+	return !srv.setIfAbsent(dedupKey(srv.AppID, msg), srv.dedupWindow)
+}
+
+//isProcessed 判断消息是否已经在去重窗口内处理过, 不记录消息
+func (srv *Server) isProcessed(msg *message.MixMessage) bool {
+	if !srv.dedupEnabled() {
+		return false
+	}
+	key := dedupKey(srv.AppID, msg)
+	//支持 Locker 的缓存通过 TryLock 记录, 获取到锁时说明没有处理过, 需要释放
+	if locker, ok := srv.Cache.(cache.Locker); ok {
+		unlock, ok, err := locker.TryLock(key, srv.dedupWindow)
+		if err != nil {
+			return false
+		}
+		if !ok {
+			return true
+		}
+		_ = unlock()
+		return false
+	}
+	return srv.Cache.IsExist(key)
+}
+
+//setIfAbsent 在缓存中记录 key, key 已经存在时返回 false
+func (srv *Server) setIfAbsent(key string, ttl time.Duration) bool {
+	//支持 Locker 的缓存通过 SET NX 原子地判断并记录
+	if locker, ok := srv.Cache.(cache.Locker); ok {
+		_, ok, err := locker.TryLock(key, ttl)
+		return err != nil || ok
+	}
+	if srv.Cache.IsExist(key) {
+		return false
+	}
+	_ = srv.Cache.Set(key, true, ttl)
+	return true
+}
+
+//dedupKey 普通消息使用 MsgId 排重, 事件推送使用 FromUserName + CreateTime + Event 排重
+//第三方平台的推送(InfoType)没有 FromUserName, 使用 AuthorizerAppid 区分不同的授权方
+func dedupKey(appID string, msg *message.MixMessage) string {
+	if msg.MsgID != 0 {
+		return fmt.Sprintf("wechat_msg_%s_%d", appID, msg.MsgID)
+	}
+	return fmt.Sprintf("wechat_event_%s_%s_%s_%d_%s%s", appID, msg.AuthorizerAppid, msg.FromUserName, msg.CreateTime, msg.Event, msg.InfoType)
+}
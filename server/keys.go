@@ -0,0 +1,72 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/antsbean/wechat/util"
+)
+
+//ErrDecryptFailed 使用所有的 EncodingAESKey 都无法解密消息
+var ErrDecryptFailed = errors.New("消息解密失败")
+
+// TokenIndex 返回校验请求时匹配的 Token: 0 表示 Context.Token, i(i>0) 表示 Context.PreviousTokens[i-1], -1 表示没有匹配
+// 用于轮换 Token 时确认微信是否已经使用新的 Token
+func (srv *Server) TokenIndex() int {
+	return srv.tokenIndex
+}
+
+// EncodingAESKeyIndex 返回解密消息时匹配的 EncodingAESKey: 0 表示 Context.EncodingAESKey,
+// i(i>0) 表示 Context.PreviousEncodingAESKeys[i-1], -1 表示没有匹配或者不是加密消息
+func (srv *Server) EncodingAESKeyIndex() int {
+	return srv.aesKeyIndex
+}
+
+//tokens 返回当前的以及轮换之前的 Token
+func (srv *Server) tokens() []string {
+	return append([]string{srv.Token}, srv.PreviousTokens...)
+}
+
+//token 返回请求匹配的 Token
+func (srv *Server) token() string {
+	if srv.tokenIndex > 0 {
+		return srv.PreviousTokens[srv.tokenIndex-1]
+	}
+	return srv.Token
+}
+
+//encodingAESKey 返回解密消息时匹配的 EncodingAESKey
+func (srv *Server) encodingAESKey() string {
+	if srv.aesKeyIndex > 0 {
+		return srv.PreviousEncodingAESKeys[srv.aesKeyIndex-1]
+	}
+	return srv.EncodingAESKey
+}
+
+//matchToken 依次使用当前的以及轮换之前的 Token 校验签名
+func (srv *Server) matchToken(signature string, strs ...string) bool {
+	for i, token := range srv.tokens() {
+		if signature == util.Signature(append([]string{token}, strs...)...) {
+			srv.tokenIndex = i
+			return true
+		}
+	}
+	srv.tokenIndex = -1
+	return false
+}
+
+//decryptMsg 依次使用当前的以及轮换之前的 EncodingAESKey 解密消息
+func (srv *Server) decryptMsg(encryptedMsg string) (rawMsgBytes []byte, err error) {
+	aesKeys := append([]string{srv.EncodingAESKey}, srv.PreviousEncodingAESKeys...)
+	for i, aesKey := range aesKeys {
+		_, rawMsgBytes, err = util.DecryptMsg(srv.AppID, encryptedMsg, aesKey)
+		if err == nil {
+			srv.aesKeyIndex = i
+			return
+		}
+	}
+	srv.aesKeyIndex = -1
+	if len(aesKeys) > 1 {
+		err = ErrDecryptFailed
+	}
+	return
+}
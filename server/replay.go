@@ -0,0 +1,52 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	//ErrInvalidSignature 请求签名校验失败
+	ErrInvalidSignature = errors.New("请求校验失败")
+	//ErrInvalidTimestamp 请求中的 timestamp 不合法
+	ErrInvalidTimestamp = errors.New("请求中的timestamp不合法")
+	//ErrTimestampExpired 请求中的 timestamp 超出了允许的时间窗口, 可能是重放的请求
+	ErrTimestampExpired = errors.New("请求已过期")
+	//ErrNonceReused 请求中的 nonce 已经使用过, 可能是重放的请求
+	ErrNonceReused = errors.New("请求中的nonce已经使用过")
+)
+
+// SetReplayWindow 开启防重放校验, 小于等于 0 时关闭, 默认关闭
+// timestamp 与当前时间相差超过 window 的请求返回 ErrTimestampExpired;
+// 设置了 Context.Cache 时, 相同的 timestamp 和 nonce 再次出现时返回 ErrNonceReused
+// 微信重试时 timestamp 和 nonce 不变, 开启了消息去重(见 SetDedupWindow)时, 去重窗口内已经处理过的消息直接回复空串,
+// 其它复用 nonce 的请求仍然返回 ErrNonceReused
+func (srv *Server) SetReplayWindow(window time.Duration) {
+	srv.replayWindow = window
+}
+
+//checkReplay 校验 timestamp 是否在时间窗口内以及 nonce 是否重复使用
+func (srv *Server) checkReplay(timestamp, nonce string) error {
+	if srv.replayWindow <= 0 {
+		return nil
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	diff := time.Since(time.Unix(ts, 0))
+	if diff > srv.replayWindow || diff < -srv.replayWindow {
+		return ErrTimestampExpired
+	}
+	if srv.Cache == nil {
+		return nil
+	}
+	//timestamp 最多可能比当前时间晚 window, nonce 需要保存两倍的时间窗口
+	key := fmt.Sprintf("wechat_nonce_%s_%s_%s", srv.AppID, timestamp, nonce)
+	if !srv.setIfAbsent(key, 2*srv.replayWindow) {
+		return ErrNonceReused
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/antsbean/wechat/message"
+)
+
+// HandlerFunc 处理消息或事件, 返回需要被动回复的消息, 不需要回复时返回 nil
+type HandlerFunc func(msg message.MixMessage) *message.Reply
+
+// Middleware 包装 HandlerFunc, 用于日志、panic 恢复、统计等
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// MatchFunc 判断消息是否由对应的 HandlerFunc 处理
+type MatchFunc func(msg *message.MixMessage) bool
+
+type route struct {
+	match   MatchFunc
+	handler HandlerFunc
+}
+
+// Router 根据消息类型、事件、EventKey 等将消息分发给不同的 HandlerFunc
+// 按照注册的顺序匹配, 由第一个匹配的 HandlerFunc 处理, 都不匹配时交给 Fallback 设置的 HandlerFunc
+// 通过 srv.SetMessageHandler(router.ServeMessage) 使用
+type Router struct {
+	routes      []route
+	fallback    HandlerFunc
+	middlewares []Middleware
+}
+
+// NewRouter init
+func NewRouter() *Router {
+	return new(Router)
+}
+
+// Use 添加中间件, 先添加的在外层
+func (r *Router) Use(middlewares ...Middleware) *Router {
+	r.middlewares = append(r.middlewares, middlewares...)
+	return r
+}
+
+// On 注册自定义匹配规则的 HandlerFunc
+func (r *Router) On(match MatchFunc, handler HandlerFunc) *Router {
+	r.routes = append(r.routes, route{match, handler})
+	return r
+}
+
+// Fallback 设置没有匹配到任何规则时的 HandlerFunc
+func (r *Router) Fallback(handler HandlerFunc) *Router {
+	r.fallback = handler
+	return r
+}
+
+// OnMsgType 处理指定类型的消息
+func (r *Router) OnMsgType(msgType message.MsgType, handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		return msg.MsgType == msgType
+	}, handler)
+}
+
+// OnText 处理文本消息
+func (r *Router) OnText(handler HandlerFunc) *Router {
+	return r.OnMsgType(message.MsgTypeText, handler)
+}
+
+// OnTextPrefix 处理内容以 prefix 开头的文本消息
+func (r *Router) OnTextPrefix(prefix string, handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		return msg.MsgType == message.MsgTypeText && strings.HasPrefix(msg.Content, prefix)
+	}, handler)
+}
+
+// OnTextMatch 处理内容匹配正则表达式的文本消息
+func (r *Router) OnTextMatch(re *regexp.Regexp, handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		return msg.MsgType == message.MsgTypeText && re.MatchString(msg.Content)
+	}, handler)
+}
+
+// OnEvent 处理指定的事件推送
+func (r *Router) OnEvent(event message.EventType, handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		return msg.MsgType == message.MsgTypeEvent && msg.Event == event
+	}, handler)
+}
+
+// OnEventKey 处理 EventKey 为 key 的事件推送
+func (r *Router) OnEventKey(event message.EventType, key string, handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		return msg.MsgType == message.MsgTypeEvent && msg.Event == event && msg.EventKey == key
+	}, handler)
+}
+
+// OnEventKeyPrefix 处理 EventKey 以 prefix 开头的事件推送
+func (r *Router) OnEventKeyPrefix(event message.EventType, prefix string, handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		return msg.MsgType == message.MsgTypeEvent && msg.Event == event && strings.HasPrefix(msg.EventKey, prefix)
+	}, handler)
+}
+
+// OnEventKeyMatch 处理 EventKey 匹配正则表达式的事件推送
+func (r *Router) OnEventKeyMatch(event message.EventType, re *regexp.Regexp, handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		return msg.MsgType == message.MsgTypeEvent && msg.Event == event && re.MatchString(msg.EventKey)
+	}, handler)
+}
+
+// OnClick 处理点击菜单拉取消息的事件推送
+func (r *Router) OnClick(key string, handler HandlerFunc) *Router {
+	return r.OnEventKey(message.EventClick, key, handler)
+}
+
+// OnScan 处理扫描带参数二维码的事件推送, 包括已关注用户的 SCAN 事件以及未关注用户扫码后的关注事件
+// 场景值可以通过 ScanSceneKey 获取
+func (r *Router) OnScan(handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		if msg.MsgType != message.MsgTypeEvent {
+			return false
+		}
+		return msg.Event == message.EventScan ||
+			msg.Event == message.EventSubscribe && strings.HasPrefix(msg.EventKey, qrScenePrefix)
+	}, handler)
+}
+
+// OnInfoType 处理第三方平台的授权事件推送
+func (r *Router) OnInfoType(infoType message.InfoType, handler HandlerFunc) *Router {
+	return r.On(func(msg *message.MixMessage) bool {
+		return msg.InfoType == infoType
+	}, handler)
+}
+
+// ServeMessage 分发消息, 可以直接作为 Server.SetMessageHandler 的参数
+func (r *Router) ServeMessage(msg message.MixMessage) *message.Reply {
+	handler := r.dispatch
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+	return handler(msg)
+}
+
+func (r *Router) dispatch(msg message.MixMessage) *message.Reply {
+	for _, route := range r.routes {
+		if route.match(&msg) {
+			return route.handler(msg)
+		}
+	}
+	if r.fallback != nil {
+		return r.fallback(msg)
+	}
+	return nil
+}
+
+const qrScenePrefix = "qrscene_"
+
+// ScanSceneKey 返回扫码事件的场景值, 未关注用户扫码关注时 EventKey 带有 qrscene_ 前缀
+func ScanSceneKey(msg message.MixMessage) string {
+	return strings.TrimPrefix(msg.EventKey, qrScenePrefix)
+}
+
+// Recovery 返回恢复 HandlerFunc 中 panic 的中间件, 发生 panic 时调用 onPanic 并且不回复消息
+func Recovery(onPanic func(msg message.MixMessage, err error)) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(msg message.MixMessage) (reply *message.Reply) {
+			defer func() {
+				if e := recover(); e != nil {
+					reply = nil
+					if onPanic != nil {
+						onPanic(msg, fmt.Errorf("panic error: %v\n%s", e, debug.Stack()))
+					}
+				}
+			}()
+			return next(msg)
+		}
+	}
+}
+
+// Logging 返回记录每条消息处理情况的中间件
+func Logging(logf func(format string, args ...interface{})) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(msg message.MixMessage) *message.Reply {
+			start := time.Now()
+			reply := next(msg)
+			var replyType message.MsgType
+			if reply != nil {
+				replyType = reply.MsgType
+			}
+			logf("wechat message: from=%s msgType=%s event=%s eventKey=%s reply=%s elapsed=%s",
+				msg.FromUserName, msg.MsgType, msg.Event, msg.EventKey, replyType, time.Since(start))
+			return reply
+		}
+	}
+}
+
+// Metrics 返回统计消息处理耗时的中间件
+func Metrics(observe func(msg message.MixMessage, reply *message.Reply, elapsed time.Duration)) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(msg message.MixMessage) *message.Reply {
+			start := time.Now()
+			reply := next(msg)
+			observe(msg, reply, time.Since(start))
+			return reply
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/antsbean/wechat/message"
+)
+
+func reply(content string) HandlerFunc {
+	return func(msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(content)}
+	}
+}
+
+func replyContent(r *message.Reply) string {
+	if r == nil {
+		return ""
+	}
+	return string(r.MsgData.(*message.Text).Content)
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter().
+		OnTextPrefix("help", reply("help")).
+		OnTextMatch(regexp.MustCompile(`^\d+$`), reply("number")).
+		OnText(reply("text")).
+		OnClick("MENU_A", reply("click")).
+		OnEventKeyPrefix(message.EventClick, "MENU_", reply("menu")).
+		OnScan(reply("scan")).
+		OnEvent(message.EventSubscribe, reply("subscribe")).
+		OnInfoType(message.InfoTypeAuthorized, reply("authorized")).
+		Fallback(reply("fallback"))
+
+	event := func(event message.EventType, key string) message.MixMessage {
+		msg := message.MixMessage{Event: event, EventKey: key}
+		msg.MsgType = message.MsgTypeEvent
+		return msg
+	}
+	text := func(content string) message.MixMessage {
+		msg := message.MixMessage{Content: content}
+		msg.MsgType = message.MsgTypeText
+		return msg
+	}
+	tests := []struct {
+		msg  message.MixMessage
+		want string
+	}{
+		{text("help me"), "help"},
+		{text("123"), "number"},
+		{text("hello"), "text"},
+		{event(message.EventClick, "MENU_A"), "click"},
+		{event(message.EventClick, "MENU_B"), "menu"},
+		{event(message.EventScan, "123"), "scan"},
+		{event(message.EventSubscribe, "qrscene_123"), "scan"},
+		{event(message.EventSubscribe, ""), "subscribe"},
+		{message.MixMessage{InfoType: message.InfoTypeAuthorized}, "authorized"},
+		{event(message.EventView, "http://example.com"), "fallback"},
+	}
+	for _, tt := range tests {
+		if got := replyContent(router.ServeMessage(tt.msg)); got != tt.want {
+			t.Errorf("%s %s %s: expect %s, got %s", tt.msg.MsgType, tt.msg.Event, tt.msg.EventKey, tt.want, got)
+		}
+	}
+	if key := ScanSceneKey(event(message.EventSubscribe, "qrscene_123")); key != "123" {
+		t.Errorf("expect scene key 123, got %s", key)
+	}
+}
+
+func TestRouterMiddleware(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(msg message.MixMessage) *message.Reply {
+				order = append(order, name)
+				return next(msg)
+			}
+		}
+	}
+	var recovered error
+	var observed bool
+	router := NewRouter().
+		Use(trace("a"), trace("b")).
+		Use(Recovery(func(msg message.MixMessage, err error) { recovered = err })).
+		Use(Metrics(func(msg message.MixMessage, reply *message.Reply, elapsed time.Duration) { observed = true })).
+		Fallback(func(msg message.MixMessage) *message.Reply { panic("boom") })
+
+	if r := router.ServeMessage(message.MixMessage{}); r != nil {
+		t.Errorf("expect nil reply after panic, got %v", r)
+	}
+	if recovered == nil {
+		t.Error("expect panic recovered")
+	}
+	if observed {
+		t.Error("expect metrics not observed after panic")
+	}
+	if len(order) != 2 || order[0] != "a" || order[1] != "b" {
+		t.Errorf("unexpected middleware order %v", order)
+	}
+}
@@ -1,72 +1,147 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"reflect"
 	"runtime/debug"
 	"strconv"
+	"time"
 
 	"github.com/antsbean/wechat/context"
 	"github.com/antsbean/wechat/message"
 	"github.com/antsbean/wechat/util"
 )
 
+const (
+	//FormatXML 消息推送使用 xml 格式
+	FormatXML = "xml"
+	//FormatJSON 消息推送使用 json 格式, 小程序的消息推送可以配置为 json
+	FormatJSON = "json"
+)
+
 //Server struct
 type Server struct {
 	*context.Context
 
+	Writer  http.ResponseWriter
+	Request *http.Request
+
 	debug bool
 
+	dedupWindow  time.Duration
+	replayWindow time.Duration
+
+	asyncReplier  *AsyncReplier
+	asyncAccepted bool
+
 	openID string
 
 	messageHandler func(message.MixMessage) *message.Reply
 
-	requestRawXMLMsg  []byte
-	requestMsg        message.MixMessage
-	responseRawXMLMsg []byte
-	responseMsg       interface{}
+	requestRawMsg  []byte
+	requestMsg     message.MixMessage
+	responseRawMsg []byte
+	responseMsg    interface{}
 
 	isSafeMode bool
-	random     []byte
-	nonce      string
-	timestamp  int64
+	isJSON     bool
+
+	tokenIndex  int
+	aesKeyIndex int
+	nonce       string
+	timestamp   int64
 }
 
 //NewServer init
 func NewServer(context *context.Context) *Server {
 	srv := new(Server)
 	srv.Context = context
+	srv.dedupWindow = defaultDedupWindow
+	srv.aesKeyIndex = -1
 	return srv
 }
 
-// SetDebug set debug field
+// ServeHTTP 实现 http.Handler, 每个请求使用独立的 Server 处理, 可以并发地处理多个请求
+func (srv *Server) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	s := srv.clone(req, writer)
+	if err := s.Serve(); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.Send(); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+//clone 复制 srv 的配置, 用于处理一个新的请求
+func (srv *Server) clone(req *http.Request, writer http.ResponseWriter) *Server {
+	return &Server{
+		Context:        srv.Context,
+		Writer:         writer,
+		Request:        req,
+		aesKeyIndex:    -1,
+		debug:          srv.debug,
+		dedupWindow:    srv.dedupWindow,
+		replayWindow:   srv.replayWindow,
+		asyncReplier:   srv.asyncReplier,
+		messageHandler: srv.messageHandler,
+	}
+}
+
+// Query returns the keyed url query value if it exists
+func (srv *Server) Query(key string) string {
+	value, _ := srv.GetQuery(key)
+	return value
+}
+
+// GetQuery is like Query(), it returns the keyed url query value
+func (srv *Server) GetQuery(key string) (string, bool) {
+	req := srv.Request
+	if values, ok := req.URL.Query()[key]; ok && len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// SetDebug set debug field, 开启后不校验请求签名, 并以 INFO 级别输出推送的原始消息
 func (srv *Server) SetDebug(debug bool) {
 	srv.debug = debug
 }
 
 //Serve 处理微信的请求消息
 func (srv *Server) Serve() error {
-	if !srv.Validate() {
-		return fmt.Errorf("请求校验失败")
+	nonceReused := false
+	if err := srv.ValidateRequest(); err != nil {
+		//微信重试时 timestamp 和 nonce 不变, 开启去重时交给去重判断是否为重试
+		if err != ErrNonceReused || !srv.dedupEnabled() {
+			return err
+		}
+		nonceReused = true
 	}
 
 	echostr, exists := srv.GetQuery("echostr")
 	if exists {
+		if nonceReused {
+			return ErrNonceReused
+		}
 		srv.String(echostr)
 		return nil
 	}
 
-	response, err := srv.handleRequest()
+	response, err := srv.handleRequest(nonceReused)
 	if err != nil {
 		return err
 	}
 
-	//debug
+	//debug, 显式开启时使用 INFO 级别输出, 默认的 logger 不输出 DEBUG 日志
 	if srv.debug {
-		fmt.Println("request msg = ", string(srv.requestRawXMLMsg))
+		srv.Logger().Infof("request msg = %s", srv.requestRawMsg)
 	}
 
 	return srv.buildResponse(response)
@@ -74,17 +149,25 @@ func (srv *Server) Serve() error {
 
 //Validate 校验请求是否合法
 func (srv *Server) Validate() bool {
+	return srv.ValidateRequest() == nil
+}
+
+//ValidateRequest 校验请求是否合法, 返回 ErrInvalidSignature、ErrTimestampExpired、ErrNonceReused 等错误
+func (srv *Server) ValidateRequest() error {
 	if srv.debug {
-		return true
+		return nil
 	}
 	timestamp := srv.Query("timestamp")
 	nonce := srv.Query("nonce")
 	signature := srv.Query("signature")
-	return signature == util.Signature(srv.Token, timestamp, nonce)
+	if !srv.matchToken(signature, timestamp, nonce) {
+		return ErrInvalidSignature
+	}
+	return srv.checkReplay(timestamp, nonce)
 }
 
-//HandleRequest 处理微信的请求
-func (srv *Server) handleRequest() (reply *message.Reply, err error) {
+//HandleRequest 处理微信的请求, nonceReused 为 true 时只接受去重窗口内已经处理过的消息
+func (srv *Server) handleRequest(nonceReused bool) (reply *message.Reply, err error) {
 	//set isSafeMode
 	srv.isSafeMode = false
 	encryptType := srv.Query("encrypt_type")
@@ -105,6 +188,19 @@ func (srv *Server) handleRequest() (reply *message.Reply, err error) {
 		err = errors.New("消息类型转换失败")
 	}
 	srv.requestMsg = mixMessage
+	if nonceReused {
+		if !srv.isProcessed(&mixMessage) {
+			err = ErrNonceReused
+		}
+		return
+	}
+	if srv.isDuplicate(&mixMessage) {
+		return
+	}
+	if srv.asyncReplier != nil {
+		srv.handleAsync(mixMessage)
+		return
+	}
 	reply = srv.messageHandler(mixMessage)
 	return
 }
@@ -116,49 +212,72 @@ func (srv *Server) GetOpenID() string {
 
 //getMessage 解析微信返回的消息
 func (srv *Server) getMessage() (interface{}, error) {
-	var rawXMLMsgBytes []byte
-	var err error
+	body, err := ioutil.ReadAll(srv.Request.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取body失败, err=%v", err)
+	}
+	srv.isJSON = srv.detectJSON(body)
+
+	var rawMsgBytes []byte
 	if srv.isSafeMode {
-		var encryptedXMLMsg message.EncryptedXMLMsg
-		if err := xml.NewDecoder(srv.Request.Body).Decode(&encryptedXMLMsg); err != nil {
-			return nil, fmt.Errorf("从body中解析xml失败,err=%v", err)
+		var encryptedMsg message.EncryptedXMLMsg
+		if err := srv.unmarshal(body, &encryptedMsg); err != nil {
+			return nil, fmt.Errorf("从body中解析加密消息失败,err=%v", err)
 		}
 
 		//验证消息签名
 		timestamp := srv.Query("timestamp")
-		srv.timestamp, err = strconv.ParseInt(timestamp, 10, 32)
-		if err != nil {
-			return nil, err
-		}
+		//timestamp 不合法时回复消息会重新生成
+		srv.timestamp, _ = strconv.ParseInt(timestamp, 10, 64)
 		nonce := srv.Query("nonce")
 		srv.nonce = nonce
 		msgSignature := srv.Query("msg_signature")
-		msgSignatureGen := util.Signature(srv.Token, timestamp, nonce, encryptedXMLMsg.EncryptedMsg)
-		if msgSignature != msgSignatureGen {
+		if !srv.matchToken(msgSignature, timestamp, nonce, encryptedMsg.EncryptedMsg) {
 			return nil, fmt.Errorf("消息不合法，验证签名失败")
 		}
 
 		//解密
-		srv.random, rawXMLMsgBytes, err = util.DecryptMsg(srv.AppID, encryptedXMLMsg.EncryptedMsg, srv.EncodingAESKey)
+		rawMsgBytes, err = srv.decryptMsg(encryptedMsg.EncryptedMsg)
 		if err != nil {
 			return nil, fmt.Errorf("消息解密失败, err=%v", err)
 		}
 	} else {
-		rawXMLMsgBytes, err = ioutil.ReadAll(srv.Request.Body)
-		if err != nil {
-			return nil, fmt.Errorf("从body中解析xml失败, err=%v", err)
-		}
+		rawMsgBytes = body
 	}
 
-	srv.requestRawXMLMsg = rawXMLMsgBytes
+	srv.requestRawMsg = rawMsgBytes
 
-	return srv.parseRequestMessage(rawXMLMsgBytes)
+	return srv.parseRequestMessage(rawMsgBytes)
 }
 
-func (srv *Server) parseRequestMessage(rawXMLMsgBytes []byte) (msg message.MixMessage, err error) {
-	msg = message.MixMessage{}
-	err = xml.Unmarshal(rawXMLMsgBytes, &msg)
-	return
+func (srv *Server) parseRequestMessage(rawMsgBytes []byte) (msg message.MixMessage, err error) {
+	return message.DecodeMixMessage(rawMsgBytes, srv.isJSON)
+}
+
+//detectJSON 判断消息推送是否为 json 格式, 未配置 MessageFormat 时根据 body 的第一个字符判断
+func (srv *Server) detectJSON(body []byte) bool {
+	switch srv.MessageFormat {
+	case FormatJSON:
+		return true
+	case FormatXML:
+		return false
+	}
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && body[0] == '{'
+}
+
+func (srv *Server) unmarshal(data []byte, v interface{}) error {
+	if srv.isJSON {
+		return json.Unmarshal(data, v)
+	}
+	return xml.Unmarshal(data, v)
+}
+
+func (srv *Server) marshal(v interface{}) ([]byte, error) {
+	if srv.isJSON {
+		return json.Marshal(v)
+	}
+	return xml.Marshal(v)
 }
 
 //SetMessageHandler 设置用户自定义的回调方法
@@ -212,33 +331,32 @@ func (srv *Server) buildResponse(reply *message.Reply) (err error) {
 	value.MethodByName("SetCreateTime").Call(params)
 
 	srv.responseMsg = msgData
-	srv.responseRawXMLMsg, err = xml.Marshal(msgData)
+	srv.responseRawMsg, err = srv.marshal(msgData)
 	return
 }
 
 //Send 将自定义的消息发送
 func (srv *Server) Send() (err error) {
+	if srv.asyncAccepted {
+		//异步回复时先告诉微信已经收到消息, 避免超时重试
+		srv.String("success")
+		return
+	}
 	replyMsg := srv.responseMsg
-	if srv.isSafeMode {
-		//安全模式下对消息进行加密
-		var encryptedMsg []byte
-		encryptedMsg, err = util.EncryptMsg(srv.random, srv.responseRawXMLMsg, srv.AppID, srv.EncodingAESKey)
+	if srv.isSafeMode && replyMsg != nil {
+		//安全模式下对消息进行加密, 请求中没有 timestamp、nonce 时重新生成
+		crypter := message.NewCrypter(srv.AppID, srv.token(), srv.encodingAESKey())
+		replyMsg, err = crypter.EncryptWith(srv.responseRawMsg, srv.timestamp, srv.nonce)
 		if err != nil {
 			return
 		}
-		//TODO 如果获取不到timestamp nonce 则自己生成
-		timestamp := srv.timestamp
-		timestampStr := strconv.FormatInt(timestamp, 10)
-		msgSignature := util.Signature(srv.Token, timestampStr, srv.nonce, string(encryptedMsg))
-		replyMsg = message.ResponseEncryptedXMLMsg{
-			EncryptedMsg: string(encryptedMsg),
-			MsgSignature: msgSignature,
-			Timestamp:    timestamp,
-			Nonce:        srv.nonce,
-		}
 	}
 	if replyMsg != nil {
-		srv.XML(replyMsg)
+		if srv.isJSON {
+			srv.JSON(replyMsg)
+		} else {
+			srv.XML(replyMsg)
+		}
 	}
 	return
 }
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/context"
+	"github.com/antsbean/wechat/message"
+	"github.com/antsbean/wechat/util"
+)
+
+func newTextRequest(token, fromUserName, content string) *http.Request {
+	timestamp, nonce := "1500000000", "nonce"
+	body := fmt.Sprintf(`<xml><ToUserName>gh_test</ToUserName><FromUserName>%s</FromUserName><CreateTime>1500000000</CreateTime><MsgType>text</MsgType><Content>%s</Content></xml>`, fromUserName, content)
+	target := fmt.Sprintf("/callback?timestamp=%s&nonce=%s&signature=%s", timestamp, nonce, util.Signature(token, timestamp, nonce))
+	return httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+}
+
+func TestServerConcurrent(t *testing.T) {
+	srv := NewServer(&context.Context{AppID: "appid", Token: "token"})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(msg.Content)}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := fmt.Sprintf("user%d", i)
+			content := fmt.Sprintf("content%d", i)
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, newTextRequest("token", user, content))
+			body := rec.Body.String()
+			if rec.Code != http.StatusOK ||
+				!strings.Contains(body, "<ToUserName><![CDATA["+user+"]]></ToUserName>") ||
+				!strings.Contains(body, "<Content><![CDATA["+content+"]]></Content>") {
+				t.Errorf("unexpected reply for %s: %d %s", user, rec.Code, body)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestServerEcho(t *testing.T) {
+	srv := NewServer(&context.Context{Token: "token"})
+	req := newTextRequest("token", "user", "")
+	req.URL.RawQuery += "&echostr=hello"
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	if rec.Body.String() != "hello" {
+		t.Errorf("expect echostr, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, newTextRequest("wrong token", "user", "hi"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expect status 400 for invalid signature, got %d", rec.Code)
+	}
+}
+
+func TestServerSetDebug(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := &context.Context{AppID: "appid", Token: "token"}
+	//与默认的 logger 相同, 不输出 DEBUG 日志
+	ctx.SetLogger(util.NewStdLogger(log.New(&buf, "", 0)))
+	srv := NewServer(ctx)
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply { return nil })
+	srv.SetDebug(true)
+
+	srv.ServeHTTP(httptest.NewRecorder(), newTextRequest("token", "user", "hi"))
+	if !strings.Contains(buf.String(), "[INFO] request msg = ") || !strings.Contains(buf.String(), "<Content>hi</Content>") {
+		t.Errorf("expect raw message logged, got %q", buf.String())
+	}
+}
+
+func TestServerDedup(t *testing.T) {
+	srv := NewServer(&context.Context{AppID: "appid", Token: "token", Cache: cache.NewMemory()})
+	var handled int32
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		atomic.AddInt32(&handled, 1)
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("ok")}
+	})
+
+	send := func(body string) string {
+		timestamp, nonce := "1500000000", "nonce"
+		target := fmt.Sprintf("/callback?timestamp=%s&nonce=%s&signature=%s", timestamp, nonce, util.Signature("token", timestamp, nonce))
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return rec.Body.String()
+	}
+	text := `<xml><FromUserName>user</FromUserName><CreateTime>1500000000</CreateTime><MsgType>text</MsgType><Content>hi</Content><MsgId>%d</MsgId></xml>`
+	event := `<xml><FromUserName>%s</FromUserName><CreateTime>1500000000</CreateTime><MsgType>event</MsgType><Event>subscribe</Event></xml>`
+
+	if body := send(fmt.Sprintf(text, 1)); body == "" {
+		t.Error("expect reply for first message")
+	}
+	if body := send(fmt.Sprintf(text, 1)); body != "" {
+		t.Errorf("expect empty reply for retried message, got %s", body)
+	}
+	send(fmt.Sprintf(text, 2))
+	send(fmt.Sprintf(event, "user"))
+	send(fmt.Sprintf(event, "user"))
+	send(fmt.Sprintf(event, "other"))
+	if n := atomic.LoadInt32(&handled); n != 4 {
+		t.Errorf("expect 4 messages handled, got %d", n)
+	}
+
+	//第三方平台的推送没有 FromUserName, 同一秒内不同授权方的推送不能被去重
+	authorized := `<xml><AppId>component</AppId><CreateTime>1500000000</CreateTime><InfoType>authorized</InfoType><AuthorizerAppid>%s</AuthorizerAppid></xml>`
+	send(fmt.Sprintf(authorized, "authorizer1"))
+	send(fmt.Sprintf(authorized, "authorizer2"))
+	send(fmt.Sprintf(authorized, "authorizer1"))
+	if n := atomic.LoadInt32(&handled); n != 6 {
+		t.Errorf("expect 6 messages handled, got %d", n)
+	}
+
+	srv.SetDedupWindow(0)
+	send(fmt.Sprintf(text, 1))
+	if n := atomic.LoadInt32(&handled); n != 7 {
+		t.Errorf("expect dedup disabled, got %d handled", n)
+	}
+}
+
+func TestServerJSON(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	srv := NewServer(&context.Context{AppID: "appid", Token: "token", EncodingAESKey: aesKey})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(msg.Content)}
+	})
+	timestamp, nonce := "1500000000", "nonce"
+	query := fmt.Sprintf("timestamp=%s&nonce=%s&signature=%s", timestamp, nonce, util.Signature("token", timestamp, nonce))
+	plain := `{"ToUserName":"gh_test","FromUserName":"user","CreateTime":1500000000,"MsgType":"text","Content":"hi","MsgId":1}`
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/callback?"+query, strings.NewReader(plain)))
+	var reply struct {
+		ToUserName string
+		MsgType    string
+		Content    string
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil {
+		t.Fatalf("expect json reply, got %s", rec.Body.String())
+	}
+	if reply.ToUserName != "user" || reply.MsgType != "text" || reply.Content != "hi" {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	//加密的 json 消息
+	encrypted, err := util.EncryptMsg([]byte(util.RandomStr(16)), []byte(plain), "appid", aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgSignature := util.Signature("token", timestamp, nonce, string(encrypted))
+	body := fmt.Sprintf(`{"ToUserName":"gh_test","Encrypt":"%s"}`, encrypted)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/callback?encrypt_type=aes&msg_signature="+msgSignature+"&"+query, strings.NewReader(body)))
+	var encryptedReply message.ResponseEncryptedXMLMsg
+	if err := json.Unmarshal(rec.Body.Bytes(), &encryptedReply); err != nil {
+		t.Fatalf("expect encrypted json reply, got %s", rec.Body.String())
+	}
+	_, raw, err := util.DecryptMsg("appid", encryptedReply.EncryptedMsg, aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, &reply); err != nil || reply.Content != "hi" {
+		t.Errorf("unexpected decrypted reply %s", raw)
+	}
+
+	//配置为 xml 时不识别 json
+	srv.MessageFormat = FormatXML
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/callback?"+query, strings.NewReader(plain)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expect json rejected when MessageFormat is xml, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServerReplay(t *testing.T) {
+	srv := NewServer(&context.Context{AppID: "appid", Token: "token", Cache: cache.NewMemory()})
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply { return nil })
+	srv.SetReplayWindow(time.Minute)
+
+	validate := func(timestamp int64, nonce string) error {
+		ts := strconv.FormatInt(timestamp, 10)
+		target := fmt.Sprintf("/callback?timestamp=%s&nonce=%s&signature=%s", ts, nonce, util.Signature("token", ts, nonce))
+		s := srv.clone(httptest.NewRequest(http.MethodPost, target, nil), httptest.NewRecorder())
+		return s.ValidateRequest()
+	}
+	now := time.Now().Unix()
+	tests := []struct {
+		timestamp int64
+		nonce     string
+		want      error
+	}{
+		{now, "nonce1", nil},
+		{now, "nonce1", ErrNonceReused},
+		{now + 30, "nonce2", nil},
+		{now - 120, "nonce3", ErrTimestampExpired},
+		{now + 120, "nonce4", ErrTimestampExpired},
+	}
+	for _, tt := range tests {
+		if err := validate(tt.timestamp, tt.nonce); err != tt.want {
+			t.Errorf("timestamp=%d nonce=%s: expect %v, got %v", tt.timestamp, tt.nonce, tt.want, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, newTextRequest("wrong", "user", "hi"))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), ErrInvalidSignature.Error()) {
+		t.Errorf("expect ErrInvalidSignature, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	srv.SetReplayWindow(0)
+	if err := validate(now-120, "nonce1"); err != nil {
+		t.Errorf("expect replay protection disabled, got %v", err)
+	}
+}
+
+func TestServerReplayRetry(t *testing.T) {
+	srv := NewServer(&context.Context{AppID: "appid", Token: "token", Cache: cache.NewMemory()})
+	var handled int32
+	srv.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		atomic.AddInt32(&handled, 1)
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("ok")}
+	})
+	srv.SetReplayWindow(time.Minute)
+
+	//微信重试时 timestamp 和 nonce 不变
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	send := func(msgID int) *httptest.ResponseRecorder {
+		target := fmt.Sprintf("/callback?timestamp=%s&nonce=nonce&signature=%s", timestamp, util.Signature("token", timestamp, "nonce"))
+		body := fmt.Sprintf(`<xml><FromUserName>user</FromUserName><CreateTime>%s</CreateTime><MsgType>text</MsgType><Content>hi</Content><MsgId>%d</MsgId></xml>`, timestamp, msgID)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := send(1); rec.Code != http.StatusOK || rec.Body.String() == "" {
+		t.Errorf("expect reply for first message, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := send(1); rec.Code != http.StatusOK || rec.Body.String() != "" {
+		t.Errorf("expect empty reply for retried message, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := send(2); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), ErrNonceReused.Error()) {
+		t.Errorf("expect ErrNonceReused for another message with the same nonce, got %d %s", rec.Code, rec.Body.String())
+	}
+	if n := atomic.LoadInt32(&handled); n != 1 {
+		t.Errorf("expect 1 message handled, got %d", n)
+	}
+
+	srv.SetDedupWindow(0)
+	if rec := send(1); rec.Code != http.StatusBadRequest {
+		t.Errorf("expect ErrNonceReused when dedup disabled, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServerKeyRotation(t *testing.T) {
+	const (
+		oldAESKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+		newAESKey = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789abcdefg"
+	)
+	ctx := &context.Context{
+		AppID:                   "appid",
+		Token:                   "new_token",
+		EncodingAESKey:          newAESKey,
+		PreviousTokens:          []string{"old_token"},
+		PreviousEncodingAESKeys: []string{oldAESKey},
+	}
+	handler := NewServer(ctx)
+	handler.SetMessageHandler(func(msg message.MixMessage) *message.Reply {
+		return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText(msg.Content)}
+	})
+
+	serve := func(token, aesKey string) (*Server, *httptest.ResponseRecorder) {
+		timestamp, nonce := "1500000000", "nonce"
+		plain := `<xml><ToUserName>gh_test</ToUserName><FromUserName>user</FromUserName><CreateTime>1500000000</CreateTime><MsgType>text</MsgType><Content>hi</Content></xml>`
+		encrypted, _ := util.EncryptMsg([]byte(util.RandomStr(16)), []byte(plain), "appid", aesKey)
+		target := fmt.Sprintf("/callback?encrypt_type=aes&timestamp=%s&nonce=%s&signature=%s&msg_signature=%s",
+			timestamp, nonce, util.Signature(token, timestamp, nonce), util.Signature(token, timestamp, nonce, string(encrypted)))
+		body := fmt.Sprintf(`<xml><ToUserName>gh_test</ToUserName><Encrypt>%s</Encrypt></xml>`, encrypted)
+		rec := httptest.NewRecorder()
+		srv := handler.clone(httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)), rec)
+		if err := srv.Serve(); err != nil {
+			return srv, rec
+		}
+		if err := srv.Send(); err != nil {
+			t.Fatal(err)
+		}
+		return srv, rec
+	}
+
+	tests := []struct {
+		token, aesKey        string
+		tokenIndex, keyIndex int
+	}{
+		{"new_token", newAESKey, 0, 0},
+		{"old_token", oldAESKey, 1, 1},
+		{"new_token", oldAESKey, 0, 1},
+		{"other_token", newAESKey, -1, -1},
+	}
+	for _, tt := range tests {
+		srv, rec := serve(tt.token, tt.aesKey)
+		if srv.TokenIndex() != tt.tokenIndex || srv.EncodingAESKeyIndex() != tt.keyIndex {
+			t.Errorf("%s: expect token %d key %d, got token %d key %d", tt.token, tt.tokenIndex, tt.keyIndex, srv.TokenIndex(), srv.EncodingAESKeyIndex())
+		}
+		if tt.tokenIndex < 0 {
+			continue
+		}
+		//回复使用匹配的 Token 签名, 使用匹配的 EncodingAESKey 加密
+		var reply message.ResponseEncryptedXMLMsg
+		if err := xml.Unmarshal(rec.Body.Bytes(), &reply); err != nil {
+			t.Fatalf("unexpected reply %s", rec.Body.String())
+		}
+		timestamp := strconv.FormatInt(reply.Timestamp, 10)
+		if reply.MsgSignature != util.Signature(tt.token, timestamp, reply.Nonce, reply.EncryptedMsg) {
+			t.Errorf("%s: reply not signed with matched token", tt.token)
+		}
+		if _, _, err := util.DecryptMsg("appid", reply.EncryptedMsg, tt.aesKey); err != nil {
+			t.Errorf("%s: reply not encrypted with matched key: %v", tt.token, err)
+		}
+	}
+}
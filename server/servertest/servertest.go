@@ -0,0 +1,282 @@
+//Package servertest 构造带签名的(可加密的)消息推送, 发送给 server.Server 或者任意 http.Handler, 并解析回复, 用于测试消息处理
+package servertest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/antsbean/wechat/message"
+	"github.com/antsbean/wechat/util"
+)
+
+var msgID int64 = 1000
+
+//Message 构造 msgType 对应的普通消息, 如 *message.TextMessage, 可以修改之后再发送
+func Message(msgType message.MsgType) interface{} {
+	return decode(fmt.Sprintf(`{"MsgType":%q,"MsgId":%d}`, msgType, atomic.AddInt64(&msgID, 1)))
+}
+
+//Event 构造 event 对应的事件推送, 如 *message.SubscribeEvent, 无法识别的事件返回 *message.MixMessage
+func Event(event message.EventType) interface{} {
+	return decode(fmt.Sprintf(`{"MsgType":%q,"Event":%q}`, message.MsgTypeEvent, event))
+}
+
+//DeviceEvent 构造 MsgType 为 device_event 的设备事件, 如 *message.DeviceEvent、*message.DeviceStatusEvent
+func DeviceEvent(event message.EventType) interface{} {
+	return decode(fmt.Sprintf(`{"MsgType":%q,"Event":%q}`, message.MsgTypeDeviceEvent, event))
+}
+
+//Component 构造第三方平台的推送, 如 *message.AuthorizedEvent
+func Component(infoType message.InfoType) interface{} {
+	return decode(fmt.Sprintf(`{"InfoType":%q}`, infoType))
+}
+
+func decode(data string) interface{} {
+	msg, err := message.Decode([]byte(data), true)
+	if err != nil {
+		panic(err)
+	}
+	return msg
+}
+
+//Text 构造文本消息
+func Text(content string) *message.TextMessage {
+	msg := Message(message.MsgTypeText).(*message.TextMessage)
+	msg.Content = content
+	return msg
+}
+
+//Subscribe 构造关注事件, 扫描带参数二维码关注时 eventKey 为 qrscene_ 加上场景值
+func Subscribe(eventKey string) *message.SubscribeEvent {
+	msg := Event(message.EventSubscribe).(*message.SubscribeEvent)
+	msg.EventKey = eventKey
+	return msg
+}
+
+//Click 构造点击菜单事件
+func Click(eventKey string) *message.ClickEvent {
+	msg := Event(message.EventClick).(*message.ClickEvent)
+	msg.EventKey = eventKey
+	return msg
+}
+
+//Client 将消息签名(以及加密)之后发送给 Handler
+type Client struct {
+	Handler http.Handler
+	Path    string //请求的 url path, 默认为 /
+
+	AppID          string
+	Token          string
+	EncodingAESKey string
+	Encrypt        bool //是否使用安全模式加密消息
+	JSON           bool //是否使用 json 格式
+
+	ToUserName string //公众号的原始ID, 默认为 gh_test
+	OpenID     string //发送消息的用户, 默认为 openid
+}
+
+//NewClient init
+func NewClient(handler http.Handler, appID, token, encodingAESKey string) *Client {
+	return &Client{
+		Handler:        handler,
+		Path:           "/",
+		AppID:          appID,
+		Token:          token,
+		EncodingAESKey: encodingAESKey,
+		ToUserName:     "gh_test",
+		OpenID:         "openid",
+	}
+}
+
+//header 消息中通用的字段, message 包中的消息以及事件都实现了
+type header interface {
+	SetToUserName(toUserName message.CDATA)
+	SetFromUserName(fromUserName message.CDATA)
+	SetCreateTime(createTime int64)
+}
+
+//Verify 发送校验 url 的请求, 返回响应的内容
+func (c *Client) Verify(echostr string) (string, error) {
+	query := c.signedQuery()
+	query.Set("echostr", echostr)
+	resp := c.do(http.MethodGet, query, "")
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("http status %d: %s", resp.StatusCode, resp.Body)
+	}
+	return string(resp.Body), nil
+}
+
+//Send 发送消息, msg 一般为 message 包中的结构体, 会使用 Client 的 ToUserName、OpenID 以及当前时间填充
+//安全模式下回复会被校验并解密
+func (c *Client) Send(msg interface{}) (*Response, error) {
+	if h, ok := msg.(header); ok {
+		h.SetToUserName(message.CDATA(c.ToUserName))
+		h.SetFromUserName(message.CDATA(c.OpenID))
+		h.SetCreateTime(util.GetCurrTs())
+	}
+	raw, err := c.marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return c.SendRaw(raw)
+}
+
+//SendRaw 发送明文消息, 安全模式下会先加密
+func (c *Client) SendRaw(raw []byte) (*Response, error) {
+	query := c.signedQuery()
+	query.Set("openid", c.OpenID)
+	body := raw
+	var crypter *message.Crypter
+	if c.Encrypt {
+		crypter = message.NewCrypter(c.AppID, c.Token, c.EncodingAESKey)
+		timestamp, _ := strconv.ParseInt(query.Get("timestamp"), 10, 64)
+		envelope, err := crypter.EncryptWith(raw, timestamp, query.Get("nonce"))
+		if err != nil {
+			return nil, err
+		}
+		body, err = c.marshal(message.EncryptedXMLMsg{
+			ToUserName:   c.ToUserName,
+			EncryptedMsg: envelope.EncryptedMsg,
+		})
+		if err != nil {
+			return nil, err
+		}
+		query.Set("encrypt_type", "aes")
+		query.Set("msg_signature", envelope.MsgSignature)
+	}
+
+	resp := c.do(http.MethodPost, query, string(body))
+	if resp.StatusCode != http.StatusOK {
+		return resp, fmt.Errorf("http status %d: %s", resp.StatusCode, resp.Body)
+	}
+	if crypter != nil && !resp.IsEmpty() {
+		var envelope message.ResponseEncryptedXMLMsg
+		if err := c.unmarshal(resp.Body, &envelope); err != nil {
+			return resp, fmt.Errorf("解析加密回复失败, err=%v", err)
+		}
+		plain, err := crypter.DecryptResponse(&envelope)
+		if err != nil {
+			return resp, err
+		}
+		resp.Encrypted = &envelope
+		resp.Body = plain
+	}
+	return resp, nil
+}
+
+func (c *Client) signedQuery() url.Values {
+	timestamp := strconv.FormatInt(util.GetCurrTs(), 10)
+	nonce := util.RandomStr(16)
+	query := url.Values{}
+	query.Set("timestamp", timestamp)
+	query.Set("nonce", nonce)
+	query.Set("signature", util.Signature(c.Token, timestamp, nonce))
+	return query
+}
+
+func (c *Client) do(method string, query url.Values, body string) *Response {
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	req := httptest.NewRequest(method, path+"?"+query.Encode(), strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c.Handler.ServeHTTP(rec, req)
+	return &Response{
+		StatusCode: rec.Code,
+		Header:     rec.Header(),
+		Body:       rec.Body.Bytes(),
+		isJSON:     c.JSON,
+	}
+}
+
+func (c *Client) marshal(v interface{}) ([]byte, error) {
+	if c.JSON {
+		return json.Marshal(v)
+	}
+	return xml.Marshal(v)
+}
+
+func (c *Client) unmarshal(data []byte, v interface{}) error {
+	if c.JSON {
+		return json.Unmarshal(data, v)
+	}
+	return xml.Unmarshal(data, v)
+}
+
+//Response 消息推送的响应
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte //回复的明文消息, 安全模式下为解密之后的内容
+
+	Encrypted *message.ResponseEncryptedXMLMsg //安全模式下的加密回复
+
+	isJSON bool
+}
+
+//IsEmpty 是否没有回复消息(空串或者 success)
+func (resp *Response) IsEmpty() bool {
+	body := strings.TrimSpace(string(resp.Body))
+	return body == "" || body == "success"
+}
+
+//Reply 根据 MsgType 将回复解析为 message 包中对应的结构体, 如 *message.Text、*message.News
+func (resp *Response) Reply() (interface{}, error) {
+	if resp.IsEmpty() {
+		return nil, nil
+	}
+	unmarshal := xml.Unmarshal
+	if resp.isJSON {
+		unmarshal = json.Unmarshal
+	}
+	var header struct {
+		MsgType message.MsgType `xml:"MsgType" json:"MsgType"`
+	}
+	if err := unmarshal(resp.Body, &header); err != nil {
+		return nil, err
+	}
+	var reply interface{}
+	switch header.MsgType {
+	case message.MsgTypeText:
+		reply = new(message.Text)
+	case message.MsgTypeImage:
+		reply = new(message.Image)
+	case message.MsgTypeVoice:
+		reply = new(message.Voice)
+	case message.MsgTypeVideo:
+		reply = new(message.Video)
+	case message.MsgTypeMusic:
+		reply = new(message.Music)
+	case message.MsgTypeNews:
+		reply = new(message.News)
+	case message.MsgTypeTransfer:
+		reply = new(message.TransferCustomer)
+	default:
+		return nil, message.ErrUnsupportReply
+	}
+	if err := unmarshal(resp.Body, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+//Text 将回复解析为文本消息
+func (resp *Response) Text() (*message.Text, error) {
+	reply, err := resp.Reply()
+	if err != nil {
+		return nil, err
+	}
+	text, ok := reply.(*message.Text)
+	if !ok {
+		return nil, fmt.Errorf("回复不是文本消息: %s", resp.Body)
+	}
+	return text, nil
+}
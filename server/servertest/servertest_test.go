@@ -0,0 +1,96 @@
+package servertest
+
+import (
+	"testing"
+
+	"github.com/antsbean/wechat/context"
+	"github.com/antsbean/wechat/message"
+	"github.com/antsbean/wechat/server"
+)
+
+func TestClient(t *testing.T) {
+	const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	srv := server.NewServer(&context.Context{AppID: "appid", Token: "token", EncodingAESKey: aesKey})
+	router := server.NewRouter().
+		OnText(func(msg message.MixMessage) *message.Reply {
+			return &message.Reply{MsgType: message.MsgTypeText, MsgData: message.NewText("re:" + msg.Content)}
+		}).
+		OnClick("NEWS", func(msg message.MixMessage) *message.Reply {
+			news := message.NewNews([]*message.Article{message.NewArticle("title", "", "", "")})
+			return &message.Reply{MsgType: message.MsgTypeNews, MsgData: news}
+		})
+	srv.SetMessageHandler(router.ServeMessage)
+
+	for _, mode := range []struct{ encrypt, json bool }{{false, false}, {true, false}, {false, true}, {true, true}} {
+		client := NewClient(srv, "appid", "token", aesKey)
+		client.Encrypt = mode.encrypt
+		client.JSON = mode.json
+
+		if echo, err := client.Verify("echo"); err != nil || echo != "echo" {
+			t.Errorf("%+v: unexpected echo %s, err=%v", mode, echo, err)
+		}
+
+		resp, err := client.Send(Text("hi"))
+		if err != nil {
+			t.Fatalf("%+v: %v", mode, err)
+		}
+		if mode.encrypt != (resp.Encrypted != nil) {
+			t.Errorf("%+v: unexpected encrypted reply %+v", mode, resp.Encrypted)
+		}
+		text, err := resp.Text()
+		if err != nil || text.Content != "re:hi" || text.ToUserName != "openid" || text.FromUserName != "gh_test" {
+			t.Errorf("%+v: unexpected reply %+v, err=%v", mode, text, err)
+		}
+
+		resp, err = client.Send(Click("NEWS"))
+		if err != nil {
+			t.Fatalf("%+v: %v", mode, err)
+		}
+		reply, err := resp.Reply()
+		if news, ok := reply.(*message.News); err != nil || !ok || len(news.Articles) != 1 || news.Articles[0].Title != "title" {
+			t.Errorf("%+v: unexpected news reply %#v, err=%v", mode, reply, err)
+		}
+
+		resp, err = client.Send(Event(message.EventUnsubscribe))
+		if err != nil || !resp.IsEmpty() {
+			t.Errorf("%+v: expect empty reply, got %s, err=%v", mode, resp.Body, err)
+		}
+	}
+}
+
+func TestBuilders(t *testing.T) {
+	if _, ok := Event(message.EventTemplateSendJobFinish).(*message.TemplateSendJobFinishEvent); !ok {
+		t.Error("expect *message.TemplateSendJobFinishEvent")
+	}
+	if _, ok := Component(message.InfoTypeVerifyTicket).(*message.ComponentVerifyTicketEvent); !ok {
+		t.Error("expect *message.ComponentVerifyTicketEvent")
+	}
+	if msg, ok := Message(message.MsgTypeImage).(*message.ImageMessage); !ok || msg.MsgType != message.MsgTypeImage || msg.MsgID == 0 {
+		t.Errorf("unexpected image message %#v", msg)
+	}
+	if msg := Subscribe("qrscene_1"); msg.Event != message.EventSubscribe || msg.EventKey != "qrscene_1" {
+		t.Errorf("unexpected subscribe event %#v", msg)
+	}
+	if msg, ok := DeviceEvent(message.EventDeviceSubscribeStatus).(*message.DeviceStatusEvent); !ok || msg.MsgType != message.MsgTypeDeviceEvent {
+		t.Errorf("unexpected device status event %#v", msg)
+	}
+}
+
+func TestDeviceEvent(t *testing.T) {
+	srv := server.NewServer(&context.Context{AppID: "appid", Token: "token"})
+	var got interface{}
+	srv.SetMessageHandler(func(mix message.MixMessage) *message.Reply {
+		got, _ = mix.Typed()
+		return nil
+	})
+
+	ev := DeviceEvent(message.EventDeviceBind).(*message.DeviceEvent)
+	ev.DeviceID = "dev1"
+	ev.Content = "aGk="
+	if _, err := NewClient(srv, "appid", "token", "").Send(ev); err != nil {
+		t.Fatal(err)
+	}
+	if bind, ok := got.(*message.DeviceEvent); !ok || bind.Event != message.EventDeviceBind || bind.DeviceID != "dev1" || bind.Content != "aGk=" || bind.FromUserName != "openid" {
+		t.Errorf("unexpected device event %#v", got)
+	}
+}
@@ -199,7 +199,7 @@ func (tcb *Tcb) DatabaseMigrateImport(req *DatabaseMigrateImportReq) (*DatabaseM
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseMigrateImportURL, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -216,7 +216,7 @@ func (tcb *Tcb) DatabaseMigrateExport(req *DatabaseMigrateExportReq) (*DatabaseM
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseMigrateExportURL, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -233,7 +233,7 @@ func (tcb *Tcb) DatabaseMigrateQueryInfo(env string, jobID int64) (*DatabaseMigr
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseMigrateQueryInfoURL, accessToken)
-	response, err := util.PostJSON(uri, map[string]interface{}{
+	response, err := tcb.HTTPClient().PostJSON(uri, map[string]interface{}{
 		"env":    env,
 		"job_id": jobID,
 	})
@@ -253,7 +253,7 @@ func (tcb *Tcb) UpdateIndex(req *UpdateIndexReq) error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", updateIndexURL, accessToken)
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return err
 	}
@@ -268,7 +268,7 @@ func (tcb *Tcb) DatabaseCollectionAdd(env, collectionName string) error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseCollectionAddURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseCollectionReq{
+	response, err := tcb.HTTPClient().PostJSON(uri, &DatabaseCollectionReq{
 		Env:            env,
 		CollectionName: collectionName,
 	})
@@ -286,7 +286,7 @@ func (tcb *Tcb) DatabaseCollectionDelete(env, collectionName string) error {
 		return err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseCollectionDeleteURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseCollectionReq{
+	response, err := tcb.HTTPClient().PostJSON(uri, &DatabaseCollectionReq{
 		Env:            env,
 		CollectionName: collectionName,
 	})
@@ -304,7 +304,7 @@ func (tcb *Tcb) DatabaseCollectionGet(env string, limit, offset int64) (*Databas
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseCollectionGetURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseCollectionGetReq{
+	response, err := tcb.HTTPClient().PostJSON(uri, &DatabaseCollectionGetReq{
 		Env:    env,
 		Limit:  limit,
 		Offset: offset,
@@ -325,7 +325,7 @@ func (tcb *Tcb) DatabaseAdd(env, query string) (*DatabaseAddRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseAddURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.HTTPClient().PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -345,7 +345,7 @@ func (tcb *Tcb) DatabaseDelete(env, query string) (*DatabaseDeleteRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseDeleteURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.HTTPClient().PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -365,7 +365,7 @@ func (tcb *Tcb) DatabaseUpdate(env, query string) (*DatabaseUpdateRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseUpdateURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.HTTPClient().PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -385,7 +385,7 @@ func (tcb *Tcb) DatabaseQuery(env, query string) (*DatabaseQueryRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseQueryURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.HTTPClient().PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
@@ -405,7 +405,7 @@ func (tcb *Tcb) DatabaseCount(env, query string) (*DatabaseCountRes, error) {
 		return nil, err
 	}
 	uri := fmt.Sprintf("%s?access_token=%s", databaseCountURL, accessToken)
-	response, err := util.PostJSON(uri, &DatabaseReq{
+	response, err := tcb.HTTPClient().PostJSON(uri, &DatabaseReq{
 		Env:   env,
 		Query: query,
 	})
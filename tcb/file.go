@@ -82,7 +82,7 @@ func (tcb *Tcb) UploadFile(env, path string) (*UploadFileRes, error) {
 		Env:  env,
 		Path: path,
 	}
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -103,7 +103,7 @@ func (tcb *Tcb) BatchDownloadFile(env string, fileList []*DownloadFile) (*BatchD
 		Env:      env,
 		FileList: fileList,
 	}
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +124,7 @@ func (tcb *Tcb) BatchDeleteFile(env string, fileIDList []string) (*BatchDeleteFi
 		Env:        env,
 		FileIDList: fileIDList,
 	}
-	response, err := util.PostJSON(uri, req)
+	response, err := tcb.HTTPClient().PostJSON(uri, req)
 	if err != nil {
 		return nil, err
 	}
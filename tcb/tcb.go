@@ -1,6 +1,10 @@
 package tcb
 
-import "github.com/antsbean/wechat/context"
+import (
+	gocontext "context"
+
+	"github.com/antsbean/wechat/context"
+)
 
 //Tcb Tencent Cloud Base
 type Tcb struct{
@@ -14,3 +18,8 @@ func NewTcb(context *context.Context)*Tcb{
 	}
 }
 
+//WithContext 返回使用 ctx 发起请求的 Tcb，ctx 取消或超时后请求会被中止
+func (tcb *Tcb) WithContext(ctx gocontext.Context) *Tcb {
+	return NewTcb(tcb.Context.WithContext(ctx))
+}
+
@@ -1,6 +1,7 @@
 package user
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -27,6 +28,11 @@ func NewUser(context *context.Context) *User {
 	return user
 }
 
+//WithContext 返回使用 ctx 发起请求的 User，ctx 取消或超时后请求会被中止
+func (user *User) WithContext(ctx gocontext.Context) *User {
+	return NewUser(user.Context.WithContext(ctx))
+}
+
 //Info 用户基本信息
 type Info struct {
 	util.CommonError
@@ -70,7 +76,7 @@ func (user *User) GetUserInfo(openID string) (userInfo *Info, err error) {
 
 	uri := fmt.Sprintf(userInfoURL, accessToken, openID)
 	var response []byte
-	response, err = util.HTTPGet(uri)
+	response, err = user.HTTPClient().HTTPGet(uri)
 	if err != nil {
 		return
 	}
@@ -80,7 +86,7 @@ func (user *User) GetUserInfo(openID string) (userInfo *Info, err error) {
 		return
 	}
 	if userInfo.ErrCode != 0 {
-		err = fmt.Errorf("GetUserInfo Error , errcode=%d , errmsg=%s", userInfo.ErrCode, userInfo.ErrMsg)
+		err = util.NewAPIError("GetUserInfo", userInfo.ErrCode, userInfo.ErrMsg)
 		return
 	}
 	return
@@ -96,7 +102,7 @@ func (user *User) UpdateRemark(openID, remark string) (err error) {
 
 	uri := fmt.Sprintf(updateRemarkURL, accessToken)
 	var response []byte
-	response, err = util.PostJSON(uri, map[string]string{"openid": openID, "remark": remark})
+	response, err = user.HTTPClient().PostJSON(uri, map[string]string{"openid": openID, "remark": remark})
 	if err != nil {
 		return
 	}
@@ -119,7 +125,7 @@ func (user *User) ListUserOpenIDs(nextOpenid ...string) (*OpenidList, error) {
 	}
 	uri.RawQuery = q.Encode()
 
-	response, err := util.HTTPGet(uri.String())
+	response, err := user.HTTPClient().HTTPGet(uri.String())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,64 @@
+package util
+
+import "strings"
+
+const (
+	//APIBaseURL 微信公众平台接口的默认域名, SDK 中的接口地址都使用该域名
+	APIBaseURL = "https://api.weixin.qq.com"
+	//APIBaseURL2 公众平台接口的容灾域名
+	APIBaseURL2 = "https://api2.weixin.qq.com"
+	//APIBaseURLShanghai 公众平台接口的上海域名
+	APIBaseURLShanghai = "https://sh.api.weixin.qq.com"
+	//APIBaseURLShenzhen 公众平台接口的深圳域名
+	APIBaseURLShenzhen = "https://sz.api.weixin.qq.com"
+	//APIBaseURLHongKong 公众平台接口的香港域名
+	APIBaseURLHongKong = "https://hk.api.weixin.qq.com"
+
+	//PayAPIBaseURL 微信支付接口的默认域名, SDK 中的支付接口地址都使用该域名
+	PayAPIBaseURL = "https://api.mch.weixin.qq.com"
+	//PayAPIBaseURL2 微信支付接口的容灾域名
+	PayAPIBaseURL2 = "https://api2.mch.weixin.qq.com"
+)
+
+// Endpoints 调用微信接口使用的域名, 可以设置为就近接入的域名、出口代理或者测试用的模拟服务
+// 发送请求时将接口地址中的默认域名替换为配置的域名, 为空时使用默认域名
+type Endpoints struct {
+	APIBaseURL    string //替换 https://api.weixin.qq.com, 例如 util.APIBaseURLShanghai
+	PayAPIBaseURL string //替换 https://api.mch.weixin.qq.com
+
+	//APIBackupURLs、PayAPIBackupURLs 发生网络错误(连接失败、超时等)时依次尝试的备用域名, 为空时不切换
+	//已经发送到服务器的请求也可能因为超时而被重新发送, 对于不能重复调用的接口需要自行判断
+	APIBackupURLs    []string
+	PayAPIBackupURLs []string
+}
+
+// DefaultBackupEndpoints 使用默认域名, 发生网络错误时切换到容灾域名
+var DefaultBackupEndpoints = Endpoints{
+	APIBackupURLs:    []string{APIBaseURL2},
+	PayAPIBackupURLs: []string{PayAPIBaseURL2},
+}
+
+//resolve 返回实际请求的地址, 配置了备用域名时依次返回使用备用域名的地址
+func (e Endpoints) resolve(uri string) []string {
+	switch {
+	case strings.HasPrefix(uri, APIBaseURL+"/"):
+		return rebase(uri, APIBaseURL, e.APIBaseURL, e.APIBackupURLs)
+	case strings.HasPrefix(uri, PayAPIBaseURL+"/"):
+		return rebase(uri, PayAPIBaseURL, e.PayAPIBaseURL, e.PayAPIBackupURLs)
+	}
+	return []string{uri}
+}
+
+//rebase 将 uri 中的 defaultBase 替换为 base 以及 backups
+func rebase(uri, defaultBase, base string, backups []string) []string {
+	path := uri[len(defaultBase):]
+	if base == "" {
+		base = defaultBase
+	}
+	uris := make([]string, 0, len(backups)+1)
+	uris = append(uris, strings.TrimSuffix(base, "/")+path)
+	for _, backup := range backups {
+		uris = append(uris, strings.TrimSuffix(backup, "/")+path)
+	}
+	return uris
+}
@@ -0,0 +1,77 @@
+package util
+
+//errCodeDescriptions 常见的全局返回码说明
+//参考 https://developers.weixin.qq.com/doc/offiaccount/Getting_Started/Global_Return_Code.html
+var errCodeDescriptions = map[int64]string{
+	-1:    "系统繁忙，此时请开发者稍候再试",
+	0:     "请求成功",
+	40001: "获取 access_token 时 AppSecret 错误，或者 access_token 无效",
+	40002: "不合法的凭证类型",
+	40003: "不合法的 OpenID",
+	40004: "不合法的媒体文件类型",
+	40005: "不合法的文件类型",
+	40006: "不合法的文件大小",
+	40007: "不合法的媒体文件 id",
+	40008: "不合法的消息类型",
+	40009: "不合法的图片文件大小",
+	40013: "不合法的 AppID",
+	40014: "不合法的 access_token",
+	40016: "不合法的按钮个数",
+	40029: "无效的 oauth_code",
+	40030: "不合法的 refresh_token",
+	40033: "不合法的请求字符，不能包含 \\uxxxx 格式的字符",
+	40035: "不合法的参数",
+	40037: "不合法的模板 id",
+	40125: "无效的 AppSecret",
+	40163: "oauth_code 已使用",
+	40164: "调用接口的 IP 地址不在白名单中",
+	41001: "缺少 access_token 参数",
+	41002: "缺少 appid 参数",
+	41004: "缺少 secret 参数",
+	41005: "缺少多媒体文件数据",
+	41006: "缺少 media_id 参数",
+	41008: "缺少 oauth code",
+	41009: "缺少 openid",
+	42001: "access_token 超时",
+	42002: "refresh_token 超时",
+	42003: "oauth_code 超时",
+	43001: "需要 GET 请求",
+	43002: "需要 POST 请求",
+	43003: "需要 HTTPS 请求",
+	43004: "需要接收者关注",
+	43005: "需要好友关系",
+	44001: "多媒体文件为空",
+	44002: "POST 的数据包为空",
+	44003: "图文消息内容为空",
+	44004: "文本消息内容为空",
+	45001: "多媒体文件大小超过限制",
+	45002: "消息内容超过限制",
+	45003: "标题字段超过限制",
+	45004: "描述字段超过限制",
+	45005: "链接字段超过限制",
+	45007: "语音播放时间超过限制",
+	45008: "图文消息超过限制",
+	45009: "接口调用超过限制",
+	45010: "创建菜单个数超过限制",
+	45011: "API 调用太频繁，请稍候再试",
+	45015: "回复时间超过限制",
+	45047: "客服接口下行条数超过上限",
+	46001: "不存在媒体数据",
+	46003: "不存在的菜单数据",
+	46004: "不存在的用户",
+	47001: "解析 JSON/XML 内容错误",
+	48001: "api 功能未授权，请确认公众号已获得该接口",
+	48004: "api 接口被封禁",
+	50001: "用户未授权该 api",
+	50002: "用户受限，可能是违规后接口被封禁",
+	61023: "refresh_token 无效",
+	85064: "找不到模板",
+	87009: "无效的签名",
+	89501: "此 IP 正在等待管理员确认",
+	89503: "此 IP 调用需要管理员确认",
+}
+
+// ErrCodeDescription 返回 errcode 的说明, 没有收录的 errcode 返回空字符串
+func ErrCodeDescription(errCode int64) string {
+	return errCodeDescriptions[errCode]
+}
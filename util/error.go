@@ -2,21 +2,100 @@ package util
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 )
 
+const (
+	//ErrCodeInvalidCredential 获取 access_token 时 AppSecret 错误，或者 access_token 无效
+	ErrCodeInvalidCredential = 40001
+	//ErrCodeInvalidOpenID 不合法的 OpenID
+	ErrCodeInvalidOpenID = 40003
+	//ErrCodeInvalidAccessToken 不合法的 access_token
+	ErrCodeInvalidAccessToken = 40014
+	//ErrCodeAccessTokenExpired access_token 超时
+	ErrCodeAccessTokenExpired = 42001
+	//ErrCodeQuotaExceeded 接口调用超过每日限制
+	ErrCodeQuotaExceeded = 45009
+	//ErrCodeFrequencyLimited 接口调用超过每分钟的频率限制
+	ErrCodeFrequencyLimited = 45011
+)
+
+// APIError 微信接口返回的 errcode 不为 0 时返回的错误, 可以通过 errors.As 获取
+type APIError struct {
+	API     string //调用的接口, 例如 GetUserInfo
+	ErrCode int64
+	ErrMsg  string
+}
+
+// NewAPIError 创建 APIError, errCode 为 0 时也会返回非 nil 的 error
+func NewAPIError(api string, errCode int64, errMsg string) *APIError {
+	return &APIError{API: api, ErrCode: errCode, ErrMsg: errMsg}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s Error , errcode=%d , errmsg=%s", e.API, e.ErrCode, e.ErrMsg)
+}
+
+// Description 返回 errcode 的说明, 没有收录的 errcode 返回空字符串
+func (e *APIError) Description() string {
+	return ErrCodeDescription(e.ErrCode)
+}
+
+// ErrCodeOf 返回 err 中的 errcode, err 不是 APIError 时返回 0
+func ErrCodeOf(err error) int64 {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrCode
+	}
+	return 0
+}
+
+// IsTokenExpired 是否为 access_token 无效或者过期导致的错误
+func IsTokenExpired(err error) bool {
+	switch ErrCodeOf(err) {
+	case ErrCodeInvalidCredential, ErrCodeInvalidAccessToken, ErrCodeAccessTokenExpired:
+		return true
+	}
+	return false
+}
+
+// IsQuotaExceeded 是否为接口调用超过每日限制或者频率限制导致的错误
+func IsQuotaExceeded(err error) bool {
+	switch ErrCodeOf(err) {
+	case ErrCodeQuotaExceeded, ErrCodeFrequencyLimited:
+		return true
+	}
+	return false
+}
+
+// IsInvalidOpenID 是否为 OpenID 不合法导致的错误
+func IsInvalidOpenID(err error) bool {
+	return ErrCodeOf(err) == ErrCodeInvalidOpenID
+}
+
 // CommonError 微信返回的通用错误json
 type CommonError struct {
 	ErrCode int64  `json:"errcode"`
 	ErrMsg  string `json:"errmsg"`
 }
 
-func (c *CommonError) Error(apiName string) error {
+// Err 返回 errcode 不为 0 时的 *APIError, errcode 为 0 时返回 nil
+func (c *CommonError) Err(apiName string) error {
 	if c.ErrCode == 0 {
 		return nil
 	}
-	return fmt.Errorf("%s Error , errcode=%d , errmsg=%s", apiName, c.ErrCode, c.ErrMsg)
+	return NewAPIError(apiName, c.ErrCode, c.ErrMsg)
+}
+
+// IsAccessTokenInvalid 是否为 access_token 失效导致的错误
+func (c *CommonError) IsAccessTokenInvalid() bool {
+	switch c.ErrCode {
+	case ErrCodeInvalidCredential, ErrCodeInvalidAccessToken, ErrCodeAccessTokenExpired:
+		return true
+	}
+	return false
 }
 
 // DecodeWithCommonError 将返回值按照CommonError解析
@@ -26,7 +105,7 @@ func DecodeWithCommonError(response []byte, apiName string) (err error) {
 	if err != nil {
 		return
 	}
-	return commError.Error(apiName)
+	return commError.Err(apiName)
 }
 
 // DecodeToCommonError 将返回值按照CommonError解析
@@ -55,7 +134,7 @@ func DecodeWithError(response []byte, obj interface{}, apiName string) error {
 		return fmt.Errorf("errcode or errmsg is invalid")
 	}
 	if errCode.Int() != 0 {
-		return fmt.Errorf("%s Error , errcode=%d , errmsg=%s", apiName, errCode.Int(), errMsg.String())
+		return NewAPIError(apiName, errCode.Int(), errMsg.String())
 	}
 	return nil
 }
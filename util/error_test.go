@@ -0,0 +1,42 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	var res struct {
+		CommonError
+		OpenID string `json:"openid"`
+	}
+	err := DecodeWithError([]byte(`{"errcode":45009,"errmsg":"reach max api daily quota limit"}`), &res, "GetUserInfo")
+	wrapped := fmt.Errorf("get user: %w", err)
+
+	var apiErr *APIError
+	if !errors.As(wrapped, &apiErr) {
+		t.Fatalf("errors.As(%v) = false", wrapped)
+	}
+	if apiErr.API != "GetUserInfo" || apiErr.ErrCode != ErrCodeQuotaExceeded || apiErr.Description() == "" {
+		t.Errorf("unexpected APIError %+v", apiErr)
+	}
+	if !IsQuotaExceeded(wrapped) || IsTokenExpired(wrapped) || IsInvalidOpenID(wrapped) {
+		t.Errorf("unexpected helpers result for %v", wrapped)
+	}
+
+	err = DecodeWithCommonError([]byte(`{"errcode":40003,"errmsg":"invalid openid"}`), "UpdateRemark")
+	if !IsInvalidOpenID(err) || ErrCodeOf(err) != ErrCodeInvalidOpenID {
+		t.Errorf("IsInvalidOpenID(%v) = false", err)
+	}
+	commErr := CommonError{ErrCode: ErrCodeAccessTokenExpired}
+	if !IsTokenExpired(commErr.Err("GetMenu")) {
+		t.Error("IsTokenExpired() = false for 42001")
+	}
+	if err := DecodeWithCommonError([]byte(`{"errcode":0,"errmsg":"ok"}`), "SetMenu"); err != nil {
+		t.Errorf("DecodeWithCommonError() = %v, want nil", err)
+	}
+	if ErrCodeOf(errors.New("other")) != 0 || IsTokenExpired(nil) {
+		t.Error("helpers should return zero values for other errors")
+	}
+}
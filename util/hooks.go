@@ -0,0 +1,81 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RequestInfo 一次接口调用的请求信息
+type RequestInfo struct {
+	API         string //接口的路径, 例如 /cgi-bin/user/info
+	Method      string
+	URL         string //access_token、secret 等参数被替换为 *** 之后的地址
+	RequestSize int    //请求体的字节数
+}
+
+// ResponseInfo 一次接口调用的结果
+type ResponseInfo struct {
+	StatusCode int           //http 状态码, 没有收到响应时为 0
+	Latency    time.Duration //从发送请求到读取完响应的耗时
+	ErrCode    int64         //json 格式的返回中的 errcode
+	BodySize   int           //响应体的字节数
+	Err        error         //网络错误、非 200 的状态码等, 不包含 errcode 不为 0 的情况
+}
+
+// Hooks 在每次调用微信接口之前和之后执行, 可以用于日志、链路追踪和监控
+// 发生网络错误切换到备用域名或者 access_token 失效重试时, 每次请求都会执行
+type Hooks struct {
+	//Before 在发送请求之前执行, 返回的 ctx 用于发送请求以及调用 After, 可以在其中保存 span 等信息
+	Before func(ctx context.Context, req *RequestInfo) context.Context
+	//After 在读取完响应之后执行
+	After func(ctx context.Context, req *RequestInfo, resp *ResponseInfo)
+}
+
+//sensitiveParams 在 RequestInfo.URL 中隐藏的参数
+var sensitiveParams = []string{
+	"access_token",
+	"component_access_token",
+	"authorizer_access_token",
+	"refresh_token",
+	"secret",
+	"corpsecret",
+	"component_appsecret",
+}
+
+// RedactURL 将 uri 中的 access_token、secret 等参数的值替换为 ***
+func RedactURL(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.RawQuery == "" {
+		return uri
+	}
+	query := u.Query()
+	redacted := false
+	for _, key := range sensitiveParams {
+		if _, ok := query[key]; ok {
+			query.Set(key, "***")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return uri
+	}
+	u.RawQuery = strings.Replace(query.Encode(), "%2A%2A%2A", "***", -1)
+	return u.String()
+}
+
+//errCodeOf 获取 json 格式的返回中的 errcode, 其它格式返回 0
+func errCodeOf(body []byte) int64 {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		return 0
+	}
+	var commError CommonError
+	if err := json.Unmarshal(body, &commError); err != nil {
+		return 0
+	}
+	return commError.ErrCode
+}
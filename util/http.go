@@ -2,6 +2,7 @@ package util
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"encoding/pem"
@@ -9,93 +10,264 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/pkcs12"
 )
 
-//HTTPGet get 请求
-func HTTPGet(uri string) ([]byte, error) {
-	response, err := http.Get(uri)
-	if err != nil {
-		return nil, err
+// Doer 执行 http 请求，*http.Client 实现了该接口
+// 可用于设置超时、代理、自定义 Transport 或在测试中替换为 mock
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// TokenRefresher 在接口返回 access_token 失效的错误码时被调用，stale 为请求中使用的 access_token
+// 返回新的 access_token 后会使用它重试一次原请求
+type TokenRefresher func(stale string) (accessToken string, err error)
+
+// HTTPClient 使用指定的 Doer 发送请求，SDK 所有的接口调用都经过这里
+type HTTPClient struct {
+	doer Doer
+	ctx  context.Context
+
+	tokenRefresher TokenRefresher
+
+	endpoints Endpoints
+
+	hooks  Hooks
+	logger Logger
+}
+
+// NewHTTPClient 实例化，doer 为 nil 时使用 http.DefaultClient
+func NewHTTPClient(doer Doer) *HTTPClient {
+	if doer == nil {
+		doer = http.DefaultClient
 	}
+	return &HTTPClient{doer: doer}
+}
 
-	defer response.Body.Close()
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http get error : uri=%v , statusCode=%v", uri, response.StatusCode)
+// WithContext 返回使用 ctx 发送请求的 client 副本，ctx 取消或超时后请求会被中止
+func (c *HTTPClient) WithContext(ctx context.Context) *HTTPClient {
+	if ctx == nil {
+		panic("nil context")
 	}
-	return ioutil.ReadAll(response.Body)
+	c2 := new(HTTPClient)
+	*c2 = *c
+	c2.ctx = ctx
+	return c2
 }
 
-//HTTPPost post 请求
-func HTTPPost(uri string, data string) ([]byte, error) {
-	body := bytes.NewBuffer([]byte(data))
-	response, err := http.Post(uri, "", body)
-	if err != nil {
-		return nil, err
+// WithTokenRefresher 返回设置了 TokenRefresher 的 client 副本
+// 请求地址中带有 access_token 且返回 40001/40014/42001 时，会通过 refresher 获取新的 access_token 并重试一次
+func (c *HTTPClient) WithTokenRefresher(refresher TokenRefresher) *HTTPClient {
+	c2 := new(HTTPClient)
+	*c2 = *c
+	c2.tokenRefresher = refresher
+	return c2
+}
+
+// WithEndpoints 返回使用 endpoints 中的域名发送请求的 client 副本
+func (c *HTTPClient) WithEndpoints(endpoints Endpoints) *HTTPClient {
+	c2 := new(HTTPClient)
+	*c2 = *c
+	c2.endpoints = endpoints
+	return c2
+}
+
+// WithHooks 返回每次请求前后执行 hooks 的 client 副本
+func (c *HTTPClient) WithHooks(hooks Hooks) *HTTPClient {
+	c2 := new(HTTPClient)
+	*c2 = *c
+	c2.hooks = hooks
+	return c2
+}
+
+// WithLogger 返回使用 logger 输出日志的 client 副本
+func (c *HTTPClient) WithLogger(logger Logger) *HTTPClient {
+	c2 := new(HTTPClient)
+	*c2 = *c
+	c2.logger = logger
+	return c2
+}
+
+// Logger 返回输出日志使用的 logger，未设置时为 DefaultLogger()
+func (c *HTTPClient) Logger() Logger {
+	if c.logger != nil {
+		return c.logger
 	}
+	return defaultLogger
+}
 
-	defer response.Body.Close()
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http get error : uri=%v , statusCode=%v", uri, response.StatusCode)
+// Context 返回发送请求使用的 ctx，未设置时为 context.Background()
+func (c *HTTPClient) Context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
 	}
-	return ioutil.ReadAll(response.Body)
+	return context.Background()
 }
 
-//PostJSON post json 数据请求
-func PostJSON(uri string, obj interface{}) ([]byte, error) {
-	jsonData, err := json.Marshal(obj)
+// defaultHTTPClient 包级别的请求方法使用的 client
+var defaultHTTPClient = NewHTTPClient(nil)
+
+// DefaultHTTPClient 返回包级别的请求方法使用的 client
+func DefaultHTTPClient() *HTTPClient {
+	return defaultHTTPClient
+}
+
+// do 发送请求并读取返回内容，非 200 的状态码视为错误
+// 接口地址中的默认域名会被替换为 Endpoints 中配置的域名，发生网络错误时依次使用备用域名重试
+func (c *HTTPClient) do(doer Doer, method, uri, contentType string, body []byte) (respBody []byte, respContentType string, err error) {
+	uris := c.endpoints.resolve(uri)
+	for i, u := range uris {
+		var networkErr bool
+		respBody, respContentType, networkErr, err = c.doOnce(doer, method, u, contentType, body)
+		if !networkErr || i == len(uris)-1 || c.Context().Err() != nil {
+			return
+		}
+	}
+	return
+}
+
+// doOnce 发送一次请求，networkErr 表示请求没有得到服务器的响应
+func (c *HTTPClient) doOnce(doer Doer, method, uri, contentType string, body []byte) (respBody []byte, respContentType string, networkErr bool, err error) {
+	ctx := c.Context()
+	var statusCode int
+	if c.hooks.Before != nil || c.hooks.After != nil {
+		info := &RequestInfo{
+			Method:      method,
+			URL:         RedactURL(uri),
+			RequestSize: len(body),
+		}
+		if u, e := url.Parse(uri); e == nil {
+			info.API = u.Path
+		}
+		if c.hooks.Before != nil {
+			ctx = c.hooks.Before(ctx, info)
+		}
+		start := time.Now()
+		defer func() {
+			if c.hooks.After != nil {
+				c.hooks.After(ctx, info, &ResponseInfo{
+					StatusCode: statusCode,
+					Latency:    time.Since(start),
+					ErrCode:    errCodeOf(respBody),
+					BodySize:   len(respBody),
+					Err:        err,
+				})
+			}
+		}()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, reader)
 	if err != nil {
-		return nil, err
+		return
 	}
-	jsonData = bytes.Replace(jsonData, []byte("\\u003c"), []byte("<"), -1)
-	jsonData = bytes.Replace(jsonData, []byte("\\u003e"), []byte(">"), -1)
-	jsonData = bytes.Replace(jsonData, []byte("\\u0026"), []byte("&"), -1)
-	body := bytes.NewBuffer(jsonData)
-	response, err := http.Post(uri, "application/json;charset=utf-8", body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	response, err := doer.Do(req)
 	if err != nil {
-		return nil, err
+		networkErr = true
+		return
 	}
 	defer response.Body.Close()
 
+	statusCode = response.StatusCode
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http get error : uri=%v , statusCode=%v", uri, response.StatusCode)
+		err = fmt.Errorf("http %s error : uri=%v , statusCode=%v", method, RedactURL(uri), response.StatusCode)
+		return
 	}
-	return ioutil.ReadAll(response.Body)
+	respBody, err = ioutil.ReadAll(response.Body)
+	respContentType = response.Header.Get("Content-Type")
+	return
 }
 
-// PostJSONWithRespContentType post json数据请求，且返回数据类型
-func PostJSONWithRespContentType(uri string, obj interface{}) ([]byte, string, error) {
-	jsonData, err := json.Marshal(obj)
+// doWithRetry 调用 send 发送请求，access_token 失效时刷新 access_token 并重试一次
+// send 每次调用都需要重新构造请求体
+func (c *HTTPClient) doWithRetry(uri string, send func(uri string) ([]byte, string, error)) ([]byte, string, error) {
+	body, contentType, err := send(uri)
+	if err != nil || c.tokenRefresher == nil {
+		return body, contentType, err
+	}
+	stale := accessTokenOf(uri)
+	if stale == "" || !isAccessTokenInvalid(body) {
+		return body, contentType, err
+	}
+	accessToken, refreshErr := c.tokenRefresher(stale)
+	if refreshErr != nil || accessToken == "" || accessToken == stale {
+		//无法刷新时返回原始的结果
+		return body, contentType, err
+	}
+	uri = strings.Replace(uri, "access_token="+stale, "access_token="+accessToken, 1)
+	return send(uri)
+}
+
+//accessTokenOf 获取请求地址中的 access_token
+func accessTokenOf(uri string) string {
+	u, err := url.Parse(uri)
 	if err != nil {
-		return nil, "", err
+		return ""
 	}
+	return u.Query().Get("access_token")
+}
 
-	jsonData = bytes.Replace(jsonData, []byte("\\u003c"), []byte("<"), -1)
-	jsonData = bytes.Replace(jsonData, []byte("\\u003e"), []byte(">"), -1)
-	jsonData = bytes.Replace(jsonData, []byte("\\u0026"), []byte("&"), -1)
+//isAccessTokenInvalid 返回内容是否为 access_token 失效的错误
+func isAccessTokenInvalid(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		return false
+	}
+	var commError CommonError
+	if err := json.Unmarshal(body, &commError); err != nil {
+		return false
+	}
+	return commError.IsAccessTokenInvalid()
+}
+
+//HTTPGet get 请求
+func (c *HTTPClient) HTTPGet(uri string) ([]byte, error) {
+	body, _, err := c.doWithRetry(uri, func(uri string) ([]byte, string, error) {
+		return c.do(c.doer, http.MethodGet, uri, "", nil)
+	})
+	return body, err
+}
+
+//HTTPPost post 请求
+func (c *HTTPClient) HTTPPost(uri string, data string) ([]byte, error) {
+	body, _, err := c.doWithRetry(uri, func(uri string) ([]byte, string, error) {
+		return c.do(c.doer, http.MethodPost, uri, "", []byte(data))
+	})
+	return body, err
+}
+
+//PostJSON post json 数据请求
+func (c *HTTPClient) PostJSON(uri string, obj interface{}) ([]byte, error) {
+	body, _, err := c.PostJSONWithRespContentType(uri, obj)
+	return body, err
+}
 
-	body := bytes.NewBuffer(jsonData)
-	response, err := http.Post(uri, "application/json;charset=utf-8", body)
+// PostJSONWithRespContentType post json数据请求，且返回数据类型
+func (c *HTTPClient) PostJSONWithRespContentType(uri string, obj interface{}) ([]byte, string, error) {
+	jsonData, err := marshalJSON(obj)
 	if err != nil {
 		return nil, "", err
 	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("http get error : uri=%v , statusCode=%v", uri, response.StatusCode)
-	}
-	responseData, err := ioutil.ReadAll(response.Body)
-	contentType := response.Header.Get("Content-Type")
-	return responseData, contentType, err
+	return c.doWithRetry(uri, func(uri string) ([]byte, string, error) {
+		return c.do(c.doer, http.MethodPost, uri, "application/json;charset=utf-8", jsonData)
+	})
 }
 
 //PostFile 上传文件
-func PostFile(fieldname, filename, uri string) ([]byte, error) {
+func (c *HTTPClient) PostFile(fieldname, filename, uri string) ([]byte, error) {
 	fields := []MultipartFormField{
 		{
 			IsFile:    true,
@@ -103,7 +275,112 @@ func PostFile(fieldname, filename, uri string) ([]byte, error) {
 			Filename:  filename,
 		},
 	}
-	return PostMultipartForm(fields, uri)
+	return c.PostMultipartForm(fields, uri)
+}
+
+//PostMultipartForm 上传文件或其他多个字段
+func (c *HTTPClient) PostMultipartForm(fields []MultipartFormField, uri string) ([]byte, error) {
+	body, _, err := c.doWithRetry(uri, func(uri string) ([]byte, string, error) {
+		bodyBuf, contentType, err := multipartForm(fields, false)
+		if err != nil {
+			return nil, "", err
+		}
+		return c.do(c.doer, http.MethodPost, uri, contentType, bodyBuf.Bytes())
+	})
+	return body, err
+}
+
+// PostMultipartFormWithBytes 上传文件或其他多个字段
+func (c *HTTPClient) PostMultipartFormWithBytes(fields []MultipartFormField, uri string) ([]byte, error) {
+	body, _, err := c.doWithRetry(uri, func(uri string) ([]byte, string, error) {
+		bodyBuf, contentType, err := multipartForm(fields, true)
+		if err != nil {
+			return nil, "", err
+		}
+		return c.do(c.doer, http.MethodPost, uri, contentType, bodyBuf.Bytes())
+	})
+	return body, err
+}
+
+//PostXML perform a HTTP/POST request with XML body
+func (c *HTTPClient) PostXML(uri string, obj interface{}) ([]byte, error) {
+	xmlData, err := xml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := c.doWithRetry(uri, func(uri string) ([]byte, string, error) {
+		return c.do(c.doer, http.MethodPost, uri, "application/xml;charset=utf-8", xmlData)
+	})
+	return body, err
+}
+
+//PostXMLWithTLS perform a HTTP/POST request with XML body and TLS
+func (c *HTTPClient) PostXMLWithTLS(uri string, obj interface{}, ca, key string) ([]byte, error) {
+	xmlData, err := xml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.httpWithTLS(ca, key)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := c.do(client, http.MethodPost, uri, "application/xml;charset=utf-8", xmlData)
+	return body, err
+}
+
+//httpWithTLS CA证书
+//如果设置的 Doer 是 *http.Client，则在其 Transport 的基础上加载证书，以保留超时、代理等配置
+func (c *HTTPClient) httpWithTLS(rootCa, key string) (*http.Client, error) {
+	certData, err := ioutil.ReadFile(rootCa)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find cert path=%s, error=%v", rootCa, err)
+	}
+	cert, err := pkcs12ToPem(certData, key)
+	if err != nil {
+		//证书不合法时仍然发送请求, 由微信返回错误
+		c.Logger().Errorf("load cert path=%s error: %v", rootCa, err)
+	}
+
+	client := &http.Client{}
+	tr := &http.Transport{}
+	if base, ok := c.doer.(*http.Client); ok {
+		*client = *base
+		if baseTr, ok := base.Transport.(*http.Transport); ok {
+			tr = baseTr.Clone()
+		}
+	}
+	if tr.TLSClientConfig == nil {
+		tr.TLSClientConfig = &tls.Config{}
+	}
+	tr.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	tr.DisableCompression = true
+	client.Transport = tr
+	return client, nil
+}
+
+//HTTPGet get 请求
+func HTTPGet(uri string) ([]byte, error) {
+	return defaultHTTPClient.HTTPGet(uri)
+}
+
+//HTTPPost post 请求
+func HTTPPost(uri string, data string) ([]byte, error) {
+	return defaultHTTPClient.HTTPPost(uri, data)
+}
+
+//PostJSON post json 数据请求
+func PostJSON(uri string, obj interface{}) ([]byte, error) {
+	return defaultHTTPClient.PostJSON(uri, obj)
+}
+
+// PostJSONWithRespContentType post json数据请求，且返回数据类型
+func PostJSONWithRespContentType(uri string, obj interface{}) ([]byte, string, error) {
+	return defaultHTTPClient.PostJSONWithRespContentType(uri, obj)
+}
+
+//PostFile 上传文件
+func PostFile(fieldname, filename, uri string) ([]byte, error) {
+	return defaultHTTPClient.PostFile(fieldname, filename, uri)
 }
 
 //MultipartFormField 保存文件或其他字段信息
@@ -116,11 +393,53 @@ type MultipartFormField struct {
 
 //PostMultipartForm 上传文件或其他多个字段
 func PostMultipartForm(fields []MultipartFormField, uri string) (respBody []byte, err error) {
-	bodyBuf := &bytes.Buffer{}
+	return defaultHTTPClient.PostMultipartForm(fields, uri)
+}
+
+// PostMultipartFormWithBytes 上传文件或其他多个字段
+func PostMultipartFormWithBytes(fields []MultipartFormField, uri string) (respBody []byte, err error) {
+	return defaultHTTPClient.PostMultipartFormWithBytes(fields, uri)
+}
+
+//PostXML perform a HTTP/POST request with XML body
+func PostXML(uri string, obj interface{}) ([]byte, error) {
+	return defaultHTTPClient.PostXML(uri, obj)
+}
+
+//PostXMLWithTLS perform a HTTP/POST request with XML body and TLS
+func PostXMLWithTLS(uri string, obj interface{}, ca, key string) ([]byte, error) {
+	return defaultHTTPClient.PostXMLWithTLS(uri, obj, ca, key)
+}
+
+//marshalJSON 序列化json，不对 <>& 进行转义
+func marshalJSON(obj interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	jsonData = bytes.Replace(jsonData, []byte("\\u003c"), []byte("<"), -1)
+	jsonData = bytes.Replace(jsonData, []byte("\\u003e"), []byte(">"), -1)
+	jsonData = bytes.Replace(jsonData, []byte("\\u0026"), []byte("&"), -1)
+	return jsonData, nil
+}
+
+//multipartForm 构造 multipart 请求体, withBytes 为 true 时所有字段都以文件的形式从 Value 中读取
+func multipartForm(fields []MultipartFormField, withBytes bool) (bodyBuf *bytes.Buffer, contentType string, err error) {
+	bodyBuf = &bytes.Buffer{}
 	bodyWriter := multipart.NewWriter(bodyBuf)
 
 	for _, field := range fields {
-		if field.IsFile {
+		switch {
+		case withBytes:
+			fileWriter, e := bodyWriter.CreateFormFile(field.Fieldname, field.Filename)
+			if e != nil {
+				err = fmt.Errorf("error writing to buffer , err=%v", e)
+				return
+			}
+			if _, err = io.Copy(fileWriter, bytes.NewReader(field.Value)); err != nil {
+				return
+			}
+		case field.IsFile:
 			fileWriter, e := bodyWriter.CreateFormFile(field.Fieldname, field.Filename)
 			if e != nil {
 				err = fmt.Errorf("error writing to buffer , err=%v", e)
@@ -132,153 +451,37 @@ func PostMultipartForm(fields []MultipartFormField, uri string) (respBody []byte
 				err = fmt.Errorf("error opening file , err=%v", e)
 				return
 			}
-			defer fh.Close()
-
-			if _, err = io.Copy(fileWriter, fh); err != nil {
+			_, err = io.Copy(fileWriter, fh)
+			fh.Close()
+			if err != nil {
 				return
 			}
-		} else {
+		default:
 			partWriter, e := bodyWriter.CreateFormField(field.Fieldname)
 			if e != nil {
 				err = e
 				return
 			}
-			valueReader := bytes.NewReader(field.Value)
-			if _, err = io.Copy(partWriter, valueReader); err != nil {
+			if _, err = io.Copy(partWriter, bytes.NewReader(field.Value)); err != nil {
 				return
 			}
 		}
 	}
 
-	contentType := bodyWriter.FormDataContentType()
-	bodyWriter.Close()
-
-	resp, e := http.Post(uri, contentType, bodyBuf)
-	if e != nil {
-		err = e
-		return
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, err
-	}
-	respBody, err = ioutil.ReadAll(resp.Body)
-	return
-}
-
-// PostMultipartFormWithBytes 上传文件或其他多个字段
-func PostMultipartFormWithBytes(fields []MultipartFormField, uri string) (respBody []byte, err error) {
-	bodyBuf := &bytes.Buffer{}
-	bodyWriter := multipart.NewWriter(bodyBuf)
-
-	for _, field := range fields {
-		fileWriter, e := bodyWriter.CreateFormFile(field.Fieldname, field.Filename)
-		if e != nil {
-			err = fmt.Errorf("error writing to buffer , err=%v", e)
-			return
-		}
-		valueReader := bytes.NewReader(field.Value)
-		if _, err = io.Copy(fileWriter, valueReader); err != nil {
-			return
-		}
-	}
-	contentType := bodyWriter.FormDataContentType()
-	bodyWriter.Close()
-
-	resp, e := http.Post(uri, contentType, bodyBuf)
-	if e != nil {
-		err = e
-		return
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, err
-	}
-	respBody, err = ioutil.ReadAll(resp.Body)
+	contentType = bodyWriter.FormDataContentType()
+	err = bodyWriter.Close()
 	return
 }
 
-//PostXML perform a HTTP/POST request with XML body
-func PostXML(uri string, obj interface{}) ([]byte, error) {
-	xmlData, err := xml.Marshal(obj)
-	if err != nil {
-		return nil, err
-	}
-
-	body := bytes.NewBuffer(xmlData)
-	response, err := http.Post(uri, "application/xml;charset=utf-8", body)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http code error : uri=%v , statusCode=%v", uri, response.StatusCode)
-	}
-	return ioutil.ReadAll(response.Body)
-}
-
-//httpWithTLS CA证书
-func httpWithTLS(rootCa, key string) (*http.Client, error) {
-	var client *http.Client
-	certData, err := ioutil.ReadFile(rootCa)
-	if err != nil {
-		return nil, fmt.Errorf("unable to find cert path=%s, error=%v", rootCa, err)
-	}
-	cert := pkcs12ToPem(certData, key)
-	config := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-	}
-	tr := &http.Transport{
-		TLSClientConfig:    config,
-		DisableCompression: true,
-	}
-	client = &http.Client{Transport: tr}
-	return client, nil
-}
-
 //pkcs12ToPem 将Pkcs12转成Pem
-func pkcs12ToPem(p12 []byte, password string) tls.Certificate {
+func pkcs12ToPem(p12 []byte, password string) (cert tls.Certificate, err error) {
 	blocks, err := pkcs12.ToPEM(p12, password)
-	defer func() {
-		if x := recover(); x != nil {
-			log.Print(x)
-		}
-	}()
 	if err != nil {
-		panic(err)
+		return
 	}
 	var pemData []byte
 	for _, b := range blocks {
 		pemData = append(pemData, pem.EncodeToMemory(b)...)
 	}
-	cert, err := tls.X509KeyPair(pemData, pemData)
-	if err != nil {
-		panic(err)
-	}
-	return cert
-}
-
-//PostXMLWithTLS perform a HTTP/POST request with XML body and TLS
-func PostXMLWithTLS(uri string, obj interface{}, ca, key string) ([]byte, error) {
-	xmlData, err := xml.Marshal(obj)
-	if err != nil {
-		return nil, err
-	}
-
-	body := bytes.NewBuffer(xmlData)
-	client, err := httpWithTLS(ca, key)
-	if err != nil {
-		return nil, err
-	}
-	response, err := client.Post(uri, "application/xml;charset=utf-8", body)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http code error : uri=%v , statusCode=%v", uri, response.StatusCode)
-	}
-	return ioutil.ReadAll(response.Body)
+	return tls.X509KeyPair(pemData, pemData)
 }
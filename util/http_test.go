@@ -0,0 +1,230 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHTTPClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(http.DefaultClient).WithEndpoints(Endpoints{APIBaseURL: ts.URL})
+
+	body, err := client.HTTPGet("https://api.weixin.qq.com/cgi-bin/token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "GET /cgi-bin/token  " {
+		t.Errorf("unexpected response %q", body)
+	}
+
+	body, contentType, err := client.PostJSONWithRespContentType("https://api.weixin.qq.com/cgi-bin/menu/create", map[string]string{"name": "<a&b>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `POST /cgi-bin/menu/create application/json;charset=utf-8 {"name":"<a&b>"}` {
+		t.Errorf("unexpected response %q", body)
+	}
+	if contentType != "application/json" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+}
+
+func TestHTTPClientStatusCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client())
+	if _, err := client.PostXML(ts.URL, struct{}{}); err == nil {
+		t.Error("expect error when status code is not 200")
+	}
+}
+
+func TestHTTPClientWithContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client := NewHTTPClient(ts.Client())
+	if _, err := client.WithContext(ctx).HTTPGet(ts.URL); err == nil {
+		t.Error("expect error when context is canceled")
+	}
+	if _, err := client.HTTPGet(ts.URL); err != nil {
+		t.Errorf("WithContext should not modify the original client, err=%v", err)
+	}
+}
+
+type xmlRequest struct {
+	XMLName xml.Name `xml:"xml"`
+	ID      string   `xml:"id"`
+}
+
+func TestHTTPClientEndpoints(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.URL.RequestURI() + " " + string(body)))
+	}))
+	defer ts.Close()
+	//关闭之后连接会被拒绝
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	client := NewHTTPClient(ts.Client()).WithEndpoints(Endpoints{
+		APIBaseURL:    ts.URL + "/",
+		PayAPIBaseURL: down.URL,
+	})
+	body, err := client.HTTPGet(APIBaseURL + "/cgi-bin/token?appid=appid")
+	if err != nil || string(body) != "/cgi-bin/token?appid=appid " {
+		t.Errorf("HTTPGet() = %q, %v", body, err)
+	}
+	if _, err := client.PostXML(PayAPIBaseURL+"/pay/orderquery", xmlRequest{ID: "1"}); err == nil {
+		t.Error("expect error when backup endpoints are not set")
+	}
+
+	client = client.WithEndpoints(Endpoints{
+		APIBaseURL:       down.URL,
+		APIBackupURLs:    []string{down.URL, ts.URL},
+		PayAPIBaseURL:    down.URL,
+		PayAPIBackupURLs: []string{ts.URL},
+	})
+	body, err = client.HTTPPost(APIBaseURL+"/cgi-bin/menu/create", "menu")
+	if err != nil || string(body) != "/cgi-bin/menu/create menu" {
+		t.Errorf("HTTPPost() = %q, %v", body, err)
+	}
+	body, err = client.PostXML(PayAPIBaseURL+"/pay/orderquery", xmlRequest{ID: "1"})
+	if err != nil || string(body) != "/pay/orderquery <xml><id>1</id></xml>" {
+		t.Errorf("PostXML() = %q, %v", body, err)
+	}
+	//其它域名不受影响
+	if _, err := client.HTTPGet(down.URL); err == nil {
+		t.Error("expect error when requesting other hosts")
+	}
+}
+
+func TestHTTPClientFailoverStatusCode(t *testing.T) {
+	var backupRequests int
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupRequests++
+	}))
+	defer backup.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.Client()).WithEndpoints(Endpoints{
+		APIBaseURL:    ts.URL,
+		APIBackupURLs: []string{backup.URL},
+	})
+	if _, err := client.HTTPGet(APIBaseURL + "/cgi-bin/token"); err == nil {
+		t.Error("expect error when status code is not 200")
+	}
+	if backupRequests != 0 {
+		t.Errorf("backup requests = %d, want 0", backupRequests)
+	}
+}
+
+type ctxKey struct{}
+
+func TestHTTPClientHooks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context() == nil {
+			t.Error("nil request context")
+		}
+		w.Write([]byte(`{"errcode":40003,"errmsg":"invalid openid"}`))
+	}))
+	defer ts.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	var requests []*RequestInfo
+	var responses []*ResponseInfo
+	client := NewHTTPClient(ts.Client()).WithEndpoints(Endpoints{
+		APIBaseURL:    down.URL,
+		APIBackupURLs: []string{ts.URL},
+	}).WithHooks(Hooks{
+		Before: func(ctx context.Context, req *RequestInfo) context.Context {
+			requests = append(requests, req)
+			return context.WithValue(ctx, ctxKey{}, len(requests))
+		},
+		After: func(ctx context.Context, req *RequestInfo, resp *ResponseInfo) {
+			if ctx.Value(ctxKey{}) != len(requests) {
+				t.Errorf("After() ctx value = %v, want %d", ctx.Value(ctxKey{}), len(requests))
+			}
+			responses = append(responses, resp)
+		},
+	})
+	body, err := client.HTTPPost(APIBaseURL+"/cgi-bin/user/info/updateremark?access_token=TOKEN&lang=zh_CN", "remark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(responses) != 2 {
+		t.Fatalf("hooks called %d/%d times, want 2", len(requests), len(responses))
+	}
+	req, resp := requests[1], responses[1]
+	if req.API != "/cgi-bin/user/info/updateremark" || req.Method != http.MethodPost || req.RequestSize != 6 {
+		t.Errorf("unexpected request info %+v", req)
+	}
+	if req.URL != ts.URL+"/cgi-bin/user/info/updateremark?access_token=***&lang=zh_CN" {
+		t.Errorf("URL = %s, access_token should be redacted", req.URL)
+	}
+	if resp.StatusCode != http.StatusOK || resp.ErrCode != 40003 || resp.BodySize != len(body) || resp.Latency <= 0 || resp.Err != nil {
+		t.Errorf("unexpected response info %+v", resp)
+	}
+	if responses[0].Err == nil || responses[0].StatusCode != 0 {
+		t.Errorf("first request should fail with network error, got %+v", responses[0])
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	cases := map[string]string{
+		"https://api.weixin.qq.com/cgi-bin/token?grant_type=client_credential&appid=APPID&secret=SECRET": "https://api.weixin.qq.com/cgi-bin/token?appid=APPID&grant_type=client_credential&secret=***",
+		"https://api.weixin.qq.com/cgi-bin/menu/get":                                                     "https://api.weixin.qq.com/cgi-bin/menu/get",
+		"https://api.weixin.qq.com/cgi-bin/user/get?next_openid=o1":                                      "https://api.weixin.qq.com/cgi-bin/user/get?next_openid=o1",
+	}
+	for uri, want := range cases {
+		if got := RedactURL(uri); got != want {
+			t.Errorf("RedactURL(%s) = %s, want %s", uri, got, want)
+		}
+	}
+}
+
+func TestHTTPClientLogger(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<xml></xml>"))
+	}))
+	defer ts.Close()
+	cert, err := ioutil.TempFile("", "cert*.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(cert.Name())
+	cert.Close()
+
+	var buf bytes.Buffer
+	client := NewHTTPClient(ts.Client()).WithLogger(NewStdLogger(log.New(&buf, "", 0)))
+	if _, err := client.PostXMLWithTLS(ts.URL, xmlRequest{ID: "1"}, cert.Name(), "mchid"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "[ERROR] load cert path=") {
+		t.Errorf("unexpected log %q", buf.String())
+	}
+}
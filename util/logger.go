@@ -0,0 +1,81 @@
+package util
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger SDK 输出日志使用的接口, *zap.SugaredLogger、*logrus.Logger 等都实现了该接口
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// StdLogger 使用标准库 *log.Logger 输出日志, 日志级别作为前缀
+type StdLogger struct {
+	*log.Logger
+	//Debug 为 true 时才输出 DEBUG 日志, DEBUG 日志中包含接口返回的内容及推送的原始消息
+	Debug bool
+}
+
+// NewStdLogger 实例化, l 为 nil 时使用标准库默认的 logger, 不输出 DEBUG 日志
+func NewStdLogger(l *log.Logger) *StdLogger {
+	return &StdLogger{Logger: l}
+}
+
+func (l *StdLogger) output(level, format string, args ...interface{}) {
+	msg := level + " " + fmt.Sprintf(format, args...)
+	if l.Logger == nil {
+		log.Output(3, msg)
+		return
+	}
+	l.Logger.Output(3, msg)
+}
+
+// Debugf Debug 为 true 时输出 DEBUG 日志
+func (l *StdLogger) Debugf(format string, args ...interface{}) {
+	if !l.Debug {
+		return
+	}
+	l.output("[DEBUG]", format, args...)
+}
+
+// Infof 输出 INFO 日志
+func (l *StdLogger) Infof(format string, args ...interface{}) {
+	l.output("[INFO]", format, args...)
+}
+
+// Warnf 输出 WARN 日志
+func (l *StdLogger) Warnf(format string, args ...interface{}) {
+	l.output("[WARN]", format, args...)
+}
+
+// Errorf 输出 ERROR 日志
+func (l *StdLogger) Errorf(format string, args ...interface{}) {
+	l.output("[ERROR]", format, args...)
+}
+
+// NopLogger 不输出任何日志
+type NopLogger struct{}
+
+// Debugf 不输出日志
+func (NopLogger) Debugf(format string, args ...interface{}) {}
+
+// Infof 不输出日志
+func (NopLogger) Infof(format string, args ...interface{}) {}
+
+// Warnf 不输出日志
+func (NopLogger) Warnf(format string, args ...interface{}) {}
+
+// Errorf 不输出日志
+func (NopLogger) Errorf(format string, args ...interface{}) {}
+
+// defaultLogger 没有设置 Logger 时使用的 logger
+var defaultLogger Logger = NewStdLogger(nil)
+
+// DefaultLogger 返回没有设置 Logger 时使用的 logger, 使用标准库默认的 logger 输出, 不输出 DEBUG 日志
+func DefaultLogger() Logger {
+	return defaultLogger
+}
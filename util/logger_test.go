@@ -0,0 +1,28 @@
+package util
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestStdLoggerDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0))
+	logger.Debugf("response = %s", "secret")
+	logger.Infof("hello")
+	if buf.String() != "[INFO] hello\n" {
+		t.Errorf("unexpected log %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.Debug = true
+	logger.Debugf("response = %s", "body")
+	if buf.String() != "[DEBUG] response = body\n" {
+		t.Errorf("unexpected log %q", buf.String())
+	}
+
+	if l, ok := DefaultLogger().(*StdLogger); !ok || l.Debug {
+		t.Errorf("default logger should drop debug logs, got %#v", DefaultLogger())
+	}
+}
@@ -1,6 +1,7 @@
 package wechat
 
 import (
+	gocontext "context"
 	"net/http"
 	"sync"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/antsbean/wechat/server"
 	"github.com/antsbean/wechat/tcb"
 	"github.com/antsbean/wechat/user"
+	"github.com/antsbean/wechat/util"
 )
 
 // Wechat struct
@@ -29,12 +31,29 @@ type Wechat struct {
 type Config struct {
 	AppID          string
 	AppSecret      string
+	UserName       string //原始ID(gh_开头), 可选, Registry 根据消息推送的 ToUserName 查找账号时使用
 	Token          string
 	EncodingAESKey string
 	PayMchID       string //支付 - 商户 ID
 	PayNotifyURL   string //支付 - 接受微信支付结果通知的接口地址
 	PayKey         string //支付 - 商户后台设置的支付 key
+	MessageFormat  string //消息推送的数据格式, server.FormatXML 或 server.FormatJSON(小程序可选), 为空时根据请求内容识别
 	Cache          cache.Cache
+	HTTPClient     util.Doer    //调用微信接口使用的 http client, 默认为 http.DefaultClient
+	Locker         cache.Locker //多个进程共享缓存时用于协调凭证刷新的分布式锁, 可选
+
+	//PreviousTokens、PreviousEncodingAESKeys 在公众平台修改 Token、EncodingAESKey 之后, 微信仍可能使用之前的值推送消息
+	//轮换期间可以在这里配置之前的值, 校验消息推送时依次尝试
+	PreviousTokens          []string
+	PreviousEncodingAESKeys []string
+
+	//Endpoints 调用微信接口使用的域名, 可以设置为就近接入的域名、代理或模拟服务, 以及发生网络错误时切换的备用域名
+	Endpoints util.Endpoints
+
+	//Logger 输出日志使用的 logger, 默认使用标准库的 log 输出
+	Logger util.Logger
+	//Hooks 每次调用微信接口前后执行, 可以用于记录日志、链路追踪以及监控接口的耗时和错误码
+	Hooks util.Hooks
 }
 
 // NewWechat init
@@ -49,19 +68,33 @@ func copyConfigToContext(cfg *Config, context *context.Context) {
 	context.AppSecret = cfg.AppSecret
 	context.Token = cfg.Token
 	context.EncodingAESKey = cfg.EncodingAESKey
+	context.PreviousTokens = cfg.PreviousTokens
+	context.PreviousEncodingAESKeys = cfg.PreviousEncodingAESKeys
 	context.PayMchID = cfg.PayMchID
 	context.PayKey = cfg.PayKey
 	context.PayNotifyURL = cfg.PayNotifyURL
+	context.MessageFormat = cfg.MessageFormat
+	context.Endpoints = cfg.Endpoints
 	context.Cache = cfg.Cache
+	context.SetHTTPClient(cfg.HTTPClient)
+	context.SetLocker(cfg.Locker)
+	context.SetLogger(cfg.Logger)
+	context.SetHooks(cfg.Hooks)
 	context.SetAccessTokenLock(new(sync.RWMutex))
 	context.SetJsAPITicketLock(new(sync.RWMutex))
 }
 
+// WithContext 返回绑定了 ctx 的 Wechat，通过它获取的各个模块调用接口时都会使用 ctx
+func (wc *Wechat) WithContext(ctx gocontext.Context) *Wechat {
+	return &Wechat{wc.Context.WithContext(ctx)}
+}
+
 // GetServer 消息管理
 func (wc *Wechat) GetServer(req *http.Request, writer http.ResponseWriter) *server.Server {
-	wc.Context.Request = req
-	wc.Context.Writer = writer
-	return server.NewServer(wc.Context)
+	srv := server.NewServer(wc.Context)
+	srv.Request = req
+	srv.Writer = writer
+	return srv
 }
 
 //GetAccessToken 获取access_token
@@ -0,0 +1,354 @@
+package wechattest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+//WXACodeImage 小程序码接口返回的图片内容
+var WXACodeImage = []byte("\xff\xd8\xff\xe0fake wxacode")
+
+// AccessToken 返回当前有效的 access_token, 还没有生成时生成一个
+func (s *Server) AccessToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken == "" || s.expired {
+		s.seq++
+		s.accessToken = fmt.Sprintf("ACCESS_TOKEN_%d", s.seq)
+		s.expired = false
+	}
+	return s.accessToken
+}
+
+// ExpireAccessToken 使当前的 access_token 失效, 之后使用它的请求返回 42001, 直到重新获取 access_token
+func (s *Server) ExpireAccessToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expired = true
+}
+
+// SetUsers 设置关注公众号的用户, 设置之后获取其它用户的信息返回 40003
+func (s *Server) SetUsers(openIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append([]string(nil), openIDs...)
+}
+
+//checkAccessToken 校验请求中的 access_token, 不合法时返回错误并且返回 false
+func (s *Server) checkAccessToken(w http.ResponseWriter, r *http.Request) bool {
+	token := r.URL.Query().Get("access_token")
+	s.mu.Lock()
+	current, expired := s.accessToken, s.expired
+	s.mu.Unlock()
+	switch {
+	case token == "":
+		writeError(w, 41001, "access_token missing")
+		return false
+	case token != current:
+		writeError(w, 40001, "invalid credential, access_token is invalid or not latest")
+		return false
+	case expired:
+		writeError(w, 42001, "access_token expired")
+		return false
+	}
+	return true
+}
+
+//checkAppSecret 校验请求中的 appid 和 secret
+func (s *Server) checkAppSecret(w http.ResponseWriter, r *http.Request, appIDKey, secretKey string) bool {
+	query := r.URL.Query()
+	if query.Get(appIDKey) != s.AppID {
+		writeError(w, 40013, "invalid appid")
+		return false
+	}
+	if query.Get(secretKey) != s.AppSecret {
+		writeError(w, 40125, "invalid appsecret")
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("grant_type") != "client_credential" {
+		writeError(w, 40002, "invalid grant_type")
+		return
+	}
+	if !s.checkAppSecret(w, r, "appid", "secret") {
+		return
+	}
+	s.mu.Lock()
+	s.seq++
+	s.accessToken = fmt.Sprintf("ACCESS_TOKEN_%d", s.seq)
+	s.expired = false
+	token := s.accessToken
+	s.mu.Unlock()
+	WriteJSON(w, map[string]interface{}{"access_token": token, "expires_in": 7200})
+}
+
+func (s *Server) handleTicket(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	ticketType := r.URL.Query().Get("type")
+	if ticketType != "jsapi" && ticketType != "wx_card" {
+		invalidArgs(w, "invalid type")
+		return
+	}
+	WriteJSON(w, map[string]interface{}{
+		"errcode":    0,
+		"errmsg":     "ok",
+		"ticket":     fmt.Sprintf("TICKET_%s_%d", ticketType, s.nextSeq()),
+		"expires_in": 7200,
+	})
+}
+
+// Menu 返回通过 /cgi-bin/menu/create 创建的菜单, 没有菜单时返回 nil
+func (s *Server) Menu() json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.menu
+}
+
+func (s *Server) handleMenuCreate(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	var menu struct {
+		Button []json.RawMessage `json:"button"`
+	}
+	if err := json.Unmarshal(body, &menu); err != nil {
+		writeError(w, 47001, "data format error")
+		return
+	}
+	if len(menu.Button) == 0 {
+		writeError(w, 40016, "invalid button size")
+		return
+	}
+	s.mu.Lock()
+	s.menu = body
+	s.mu.Unlock()
+	writeOK(w)
+}
+
+func (s *Server) handleMenuGet(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	menu := s.Menu()
+	if menu == nil {
+		writeError(w, 46003, "menu no exist")
+		return
+	}
+	WriteJSON(w, map[string]interface{}{"menu": menu})
+}
+
+func (s *Server) handleMenuDelete(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	s.mu.Lock()
+	s.menu = nil
+	s.mu.Unlock()
+	writeOK(w)
+}
+
+//isUser openID 是否为关注公众号的用户, 没有设置用户时都认为是
+func (s *Server) isUser(openID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users == nil {
+		return openID != ""
+	}
+	for _, user := range s.users {
+		if user == openID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	openID := r.URL.Query().Get("openid")
+	if !s.isUser(openID) {
+		writeError(w, 40003, "invalid openid")
+		return
+	}
+	WriteJSON(w, map[string]interface{}{
+		"subscribe":      1,
+		"openid":         openID,
+		"nickname":       "nickname_" + openID,
+		"sex":            1,
+		"language":       "zh_CN",
+		"city":           "广州",
+		"province":       "广东",
+		"country":        "中国",
+		"subscribe_time": time.Now().Unix(),
+	})
+}
+
+func (s *Server) handleUserUpdateRemark(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	var req struct {
+		OpenID string `json:"openid"`
+		Remark string `json:"remark"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 47001, "data format error")
+		return
+	}
+	if !s.isUser(req.OpenID) {
+		writeError(w, 40003, "invalid openid")
+		return
+	}
+	writeOK(w)
+}
+
+func (s *Server) handleUserList(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	s.mu.Lock()
+	users := append([]string(nil), s.users...)
+	s.mu.Unlock()
+
+	start := 0
+	if next := r.URL.Query().Get("next_openid"); next != "" {
+		for i, user := range users {
+			if user == next {
+				start = i + 1
+				break
+			}
+		}
+	}
+	//与微信一样每次最多返回 10000 个
+	end := start + 10000
+	if end > len(users) {
+		end = len(users)
+	}
+	openIDs := users[start:end]
+	nextOpenID := ""
+	if len(openIDs) > 0 {
+		nextOpenID = openIDs[len(openIDs)-1]
+	}
+	res := map[string]interface{}{
+		"total":       len(users),
+		"count":       len(openIDs),
+		"next_openid": nextOpenID,
+	}
+	if len(openIDs) > 0 {
+		res["data"] = map[string]interface{}{"openid": openIDs}
+	}
+	WriteJSON(w, res)
+}
+
+func (s *Server) handleTemplateSend(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	var msg struct {
+		ToUser     string          `json:"touser"`
+		TemplateID string          `json:"template_id"`
+		Data       json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		writeError(w, 47001, "data format error")
+		return
+	}
+	if msg.TemplateID == "" {
+		writeError(w, 40037, "invalid template_id")
+		return
+	}
+	if !s.isUser(msg.ToUser) {
+		writeError(w, 40003, "invalid openid")
+		return
+	}
+	WriteJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok", "msgid": s.nextSeq()})
+}
+
+func (s *Server) handleMediaUpload(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	mediaType := r.URL.Query().Get("type")
+	switch mediaType {
+	case "image", "voice", "video", "thumb":
+	default:
+		writeError(w, 40004, "invalid media type")
+		return
+	}
+	if _, _, err := r.FormFile("media"); err != nil {
+		writeError(w, 41005, "media data missing")
+		return
+	}
+	res := map[string]interface{}{
+		"type":       mediaType,
+		"media_id":   fmt.Sprintf("MEDIA_ID_%d", s.nextSeq()),
+		"created_at": time.Now().Unix(),
+	}
+	if mediaType == "thumb" {
+		res["thumb_media_id"] = res["media_id"]
+	}
+	WriteJSON(w, res)
+}
+
+func (s *Server) handleMediaUploadImage(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	if _, _, err := r.FormFile("media"); err != nil {
+		writeError(w, 41005, "media data missing")
+		return
+	}
+	WriteJSON(w, map[string]interface{}{
+		"url": fmt.Sprintf("http://mmbiz.qpic.cn/mmbiz/IMAGE_%d/0", s.nextSeq()),
+	})
+}
+
+func (s *Server) handleCode2Session(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAppSecret(w, r, "appid", "secret") {
+		return
+	}
+	code := r.URL.Query().Get("js_code")
+	if code == "" {
+		writeError(w, 40029, "invalid code")
+		return
+	}
+	WriteJSON(w, map[string]interface{}{
+		"openid":      "OPENID_" + code,
+		"session_key": "U0VTU0lPTl9LRVlfMDEyMw==",
+		"unionid":     "UNIONID_" + code,
+	})
+}
+
+func (s *Server) handleWXACode(w http.ResponseWriter, r *http.Request) {
+	if !s.checkAccessToken(w, r) {
+		return
+	}
+	var req struct {
+		Path  string `json:"path"`
+		Scene string `json:"scene"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, 47001, "data format error")
+		return
+	}
+	if r.URL.Path == "/wxa/getwxacodeunlimit" && req.Scene == "" {
+		invalidArgs(w, "invalid scene")
+		return
+	}
+	if r.URL.Path != "/wxa/getwxacodeunlimit" && req.Path == "" {
+		invalidArgs(w, "invalid path")
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write(WXACodeImage)
+}
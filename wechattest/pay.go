@@ -0,0 +1,341 @@
+package wechattest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antsbean/wechat/util"
+)
+
+// Order 模拟服务中的支付订单
+type Order struct {
+	OutTradeNo    string
+	TransactionID string
+	PrepayID      string
+	TradeType     string
+	TotalFee      string
+	OpenID        string
+	TradeState    string //NOTPAY、SUCCESS、REFUND 等, 下单后为 NOTPAY
+	RefundFee     string
+}
+
+// Order 返回商户订单号为 outTradeNo 的订单
+func (s *Server) Order(outTradeNo string) (Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, ok := s.orders[outTradeNo]
+	if !ok {
+		return Order{}, false
+	}
+	return *order, true
+}
+
+// SetTradeState 修改订单的状态, 例如模拟用户完成支付时设置为 SUCCESS, 订单不存在时返回 false
+func (s *Server) SetTradeState(outTradeNo, tradeState string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, ok := s.orders[outTradeNo]
+	if ok {
+		order.TradeState = tradeState
+	}
+	return ok
+}
+
+// InjectPayError 使支付接口 path 的下一个请求返回 result_code 为 FAIL 的业务错误, 例如 ORDERPAID、SYSTEMERROR
+func (s *Server) InjectPayError(path, errCode, errCodeDes string) {
+	s.HandleOnce(path, func(w http.ResponseWriter, r *http.Request) {
+		params, _ := parseXML(r.Body)
+		s.writePayResult(w, params["sign_type"], map[string]string{
+			"result_code":  "FAIL",
+			"err_code":     errCode,
+			"err_code_des": errCodeDes,
+		})
+	})
+}
+
+// PaySign 使用 PayKey 对参数签名, signType 为 HMAC-SHA256 时使用 HMAC-SHA256, 否则使用 MD5
+func (s *Server) PaySign(params map[string]string, signType string) string {
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if k == "sign" || v == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(params[k])
+		buf.WriteByte('&')
+	}
+	buf.WriteString("key=")
+	buf.WriteString(s.PayKey)
+
+	if signType != "HMAC-SHA256" {
+		return util.MD5Sum(buf.String())
+	}
+	h := hmac.New(sha256.New, []byte(s.PayKey))
+	h.Write(buf.Bytes())
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+}
+
+//parseXML 将 <xml> 下的字段解析为 map
+func parseXML(r io.Reader) (map[string]string, error) {
+	params := make(map[string]string)
+	decoder := xml.NewDecoder(r)
+	var key string
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return params, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				key = t.Name.Local
+				params[key] = ""
+			}
+		case xml.EndElement:
+			depth--
+			key = ""
+		case xml.CharData:
+			if depth == 2 && key != "" {
+				params[key] += string(t)
+			}
+		}
+	}
+}
+
+//writeXML 返回 <xml> 格式的内容, 字段按照名称排序
+func writeXML(w http.ResponseWriter, params map[string]string) {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	buf.WriteString("<xml>")
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "<%s><![CDATA[%s]]></%s>", k, params[k], k)
+	}
+	buf.WriteString("</xml>")
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(buf.Bytes())
+}
+
+func writeReturnFail(w http.ResponseWriter, returnMsg string) {
+	writeXML(w, map[string]string{"return_code": "FAIL", "return_msg": returnMsg})
+}
+
+//writePayResult 在 result 中加上公共的返回字段并签名
+func (s *Server) writePayResult(w http.ResponseWriter, signType string, result map[string]string) {
+	result["return_code"] = "SUCCESS"
+	result["return_msg"] = "OK"
+	result["appid"] = s.AppID
+	result["mch_id"] = s.PayMchID
+	result["nonce_str"] = util.RandomStr(16)
+	result["sign"] = s.PaySign(result, signType)
+	writeXML(w, result)
+}
+
+//readPayRequest 解析支付接口的请求并校验商户号和签名, 不合法时返回错误并且返回 false
+func (s *Server) readPayRequest(w http.ResponseWriter, r *http.Request) (map[string]string, bool) {
+	body, _ := ioutil.ReadAll(r.Body)
+	params, err := parseXML(bytes.NewReader(body))
+	if err != nil || len(params) == 0 {
+		writeReturnFail(w, "XML格式错误")
+		return nil, false
+	}
+	if params["appid"] != s.AppID {
+		writeReturnFail(w, "appid不存在")
+		return nil, false
+	}
+	if s.PayMchID != "" && params["mch_id"] != s.PayMchID {
+		writeReturnFail(w, "商户号mch_id与appid不匹配")
+		return nil, false
+	}
+	if params["nonce_str"] == "" {
+		writeReturnFail(w, "缺少参数nonce_str")
+		return nil, false
+	}
+	if params["sign"] != s.PaySign(params, params["sign_type"]) {
+		writeReturnFail(w, "签名错误")
+		return nil, false
+	}
+	return params, true
+}
+
+func (s *Server) handleUnifiedOrder(w http.ResponseWriter, r *http.Request) {
+	params, ok := s.readPayRequest(w, r)
+	if !ok {
+		return
+	}
+	for _, key := range []string{"body", "out_trade_no", "total_fee", "spbill_create_ip", "notify_url", "trade_type"} {
+		if params[key] == "" {
+			writeReturnFail(w, "缺少参数"+key)
+			return
+		}
+	}
+	tradeType := params["trade_type"]
+	if tradeType == "JSAPI" && params["openid"] == "" && params["sub_openid"] == "" {
+		s.writePayResult(w, params["sign_type"], map[string]string{
+			"result_code":  "FAIL",
+			"err_code":     "PARAM_ERROR",
+			"err_code_des": "JSAPI支付必须传openid",
+		})
+		return
+	}
+
+	s.mu.Lock()
+	order, exists := s.orders[params["out_trade_no"]]
+	if exists && order.TradeState != "NOTPAY" {
+		s.mu.Unlock()
+		s.writePayResult(w, params["sign_type"], map[string]string{
+			"result_code":  "FAIL",
+			"err_code":     "ORDERPAID",
+			"err_code_des": "该订单已支付",
+		})
+		return
+	}
+	if !exists {
+		s.seq++
+		order = &Order{
+			OutTradeNo:    params["out_trade_no"],
+			TransactionID: fmt.Sprintf("TRANSACTION_%d", s.seq),
+			PrepayID:      fmt.Sprintf("wx%s%d", time.Now().Format("20060102150405"), s.seq),
+			TradeType:     tradeType,
+			TotalFee:      params["total_fee"],
+			OpenID:        params["openid"],
+			TradeState:    "NOTPAY",
+		}
+		s.orders[order.OutTradeNo] = order
+	}
+	prepayID := order.PrepayID
+	s.mu.Unlock()
+
+	result := map[string]string{
+		"result_code": "SUCCESS",
+		"trade_type":  tradeType,
+		"prepay_id":   prepayID,
+	}
+	if tradeType == "NATIVE" {
+		result["code_url"] = "weixin://wxpay/bizpayurl?pr=" + prepayID
+	}
+	s.writePayResult(w, params["sign_type"], result)
+}
+
+//findOrder 根据商户订单号或微信订单号查找订单, 返回的是副本
+func (s *Server) findOrder(outTradeNo, transactionID string) (Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if order, ok := s.orders[outTradeNo]; ok {
+		return *order, true
+	}
+	for _, order := range s.orders {
+		if transactionID != "" && order.TransactionID == transactionID {
+			return *order, true
+		}
+	}
+	return Order{}, false
+}
+
+func (s *Server) handleOrderQuery(w http.ResponseWriter, r *http.Request) {
+	params, ok := s.readPayRequest(w, r)
+	if !ok {
+		return
+	}
+	order, ok := s.findOrder(params["out_trade_no"], params["transaction_id"])
+	if !ok {
+		s.writePayResult(w, params["sign_type"], map[string]string{
+			"result_code":  "FAIL",
+			"err_code":     "ORDERNOTEXIST",
+			"err_code_des": "订单不存在",
+		})
+		return
+	}
+	result := map[string]string{
+		"result_code":  "SUCCESS",
+		"out_trade_no": order.OutTradeNo,
+		"trade_type":   order.TradeType,
+		"trade_state":  order.TradeState,
+		"total_fee":    order.TotalFee,
+		"openid":       order.OpenID,
+	}
+	if order.TradeState != "NOTPAY" {
+		result["transaction_id"] = order.TransactionID
+	}
+	s.writePayResult(w, params["sign_type"], result)
+}
+
+func (s *Server) handleRefund(w http.ResponseWriter, r *http.Request) {
+	params, ok := s.readPayRequest(w, r)
+	if !ok {
+		return
+	}
+	if params["out_refund_no"] == "" || params["refund_fee"] == "" || params["total_fee"] == "" {
+		writeReturnFail(w, "缺少参数")
+		return
+	}
+	order, ok := s.findOrder(params["out_trade_no"], params["transaction_id"])
+	if !ok {
+		s.writePayResult(w, params["sign_type"], map[string]string{
+			"result_code":  "FAIL",
+			"err_code":     "ORDERNOTEXIST",
+			"err_code_des": "订单不存在",
+		})
+		return
+	}
+	if order.TradeState != "SUCCESS" {
+		s.writePayResult(w, params["sign_type"], map[string]string{
+			"result_code":  "FAIL",
+			"err_code":     "TRADE_STATE_ERROR",
+			"err_code_des": "订单状态错误",
+		})
+		return
+	}
+	if params["total_fee"] != order.TotalFee {
+		s.writePayResult(w, params["sign_type"], map[string]string{
+			"result_code":  "FAIL",
+			"err_code":     "INVALID_REQUEST",
+			"err_code_des": "订单金额或退款金额与之前请求不一致",
+		})
+		return
+	}
+
+	s.mu.Lock()
+	s.seq++
+	refundID := fmt.Sprintf("REFUND_%d", s.seq)
+	if o, ok := s.orders[order.OutTradeNo]; ok {
+		o.TradeState = "REFUND"
+		o.RefundFee = params["refund_fee"]
+	}
+	s.mu.Unlock()
+
+	s.writePayResult(w, params["sign_type"], map[string]string{
+		"result_code":    "SUCCESS",
+		"transaction_id": order.TransactionID,
+		"out_trade_no":   order.OutTradeNo,
+		"out_refund_no":  params["out_refund_no"],
+		"refund_id":      refundID,
+		"refund_fee":     params["refund_fee"],
+		"total_fee":      order.TotalFee,
+		"cash_fee":       order.TotalFee,
+	})
+}
@@ -0,0 +1,258 @@
+// Package wechattest 提供一个进程内的微信接口模拟服务, 用于在不访问真实微信服务器的情况下测试调用 SDK 的代码
+//
+// Server 模拟 api.weixin.qq.com 和 api.mch.weixin.qq.com 上常用的接口,
+// 通过 Client 返回的 *http.Client 发出的请求无论目标域名是什么都会被发送到 Server:
+//
+//	fake := wechattest.NewServer("appid", "secret")
+//	defer fake.Close()
+//	wc := wechat.NewWechat(&wechat.Config{
+//		AppID:      fake.AppID,
+//		AppSecret:  fake.AppSecret,
+//		Cache:      cache.NewMemory(),
+//		HTTPClient: fake.Client(),
+//	})
+//
+// 接口的返回可以通过 Handle、HandleOnce、InjectError 等方法修改, 收到的请求可以通过 Requests 查看
+package wechattest
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+)
+
+// Request 记录 Server 收到的请求
+type Request struct {
+	Method string
+	Host   string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Server 模拟微信接口的服务
+type Server struct {
+	AppID     string
+	AppSecret string
+	PayMchID  string //支付 - 商户 ID, 为空时不校验
+	PayKey    string //支付 - 商户后台设置的支付 key, 用于校验请求的签名以及对返回签名
+
+	ts       *httptest.Server
+	handlers map[string]http.HandlerFunc
+
+	mu          sync.Mutex
+	seq         int
+	accessToken string
+	expired     bool
+	menu        json.RawMessage
+	users       []string
+	orders      map[string]*Order
+	overrides   map[string]http.HandlerFunc
+	once        map[string][]http.HandlerFunc
+	requests    []*Request
+}
+
+// NewServer 启动模拟服务, 使用完之后需要调用 Close
+func NewServer(appID, appSecret string) *Server {
+	s := &Server{
+		AppID:     appID,
+		AppSecret: appSecret,
+		orders:    make(map[string]*Order),
+		overrides: make(map[string]http.HandlerFunc),
+		once:      make(map[string][]http.HandlerFunc),
+	}
+	s.handlers = map[string]http.HandlerFunc{
+		"/cgi-bin/token":                  s.handleToken,
+		"/cgi-bin/ticket/getticket":       s.handleTicket,
+		"/cgi-bin/menu/create":            s.handleMenuCreate,
+		"/cgi-bin/menu/get":               s.handleMenuGet,
+		"/cgi-bin/menu/delete":            s.handleMenuDelete,
+		"/cgi-bin/user/info":              s.handleUserInfo,
+		"/cgi-bin/user/info/updateremark": s.handleUserUpdateRemark,
+		"/cgi-bin/user/get":               s.handleUserList,
+		"/cgi-bin/message/template/send":  s.handleTemplateSend,
+		"/cgi-bin/media/upload":           s.handleMediaUpload,
+		"/cgi-bin/media/uploadimg":        s.handleMediaUploadImage,
+		"/sns/jscode2session":             s.handleCode2Session,
+		"/wxa/getwxacode":                 s.handleWXACode,
+		"/wxa/getwxacodeunlimit":          s.handleWXACode,
+		"/cgi-bin/wxaapp/createwxaqrcode": s.handleWXACode,
+		"/pay/unifiedorder":               s.handleUnifiedOrder,
+		"/pay/orderquery":                 s.handleOrderQuery,
+		"/secapi/pay/refund":              s.handleRefund,
+	}
+	s.ts = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL 返回模拟服务的地址
+func (s *Server) URL() string {
+	return s.ts.URL
+}
+
+// Close 关闭模拟服务
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+// Client 返回将所有请求发送到模拟服务的 *http.Client, 可以直接作为 wechat.Config.HTTPClient 使用
+// 请求的域名和路径保持不变, 退款等需要证书的接口也会使用同样的 Transport
+func (s *Server) Client() *http.Client {
+	tr := s.ts.Client().Transport.(*http.Transport).Clone()
+	addr := s.ts.Listener.Addr().String()
+	dialer := &net.Dialer{}
+	tr.DialContext = func(ctx gocontext.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	tr.TLSClientConfig = tr.TLSClientConfig.Clone()
+	//httptest 的证书包含 example.com, 请求微信的域名时按照 example.com 校验证书
+	tr.TLSClientConfig.ServerName = "example.com"
+	return &http.Client{Transport: tr}
+}
+
+// Handle 使用 handler 处理 path 的请求, 代替默认的实现, handler 为 nil 时恢复默认的实现
+// path 不包含域名和参数, 例如 /cgi-bin/menu/create
+func (s *Server) Handle(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if handler == nil {
+		delete(s.overrides, path)
+		return
+	}
+	s.overrides[path] = handler
+}
+
+// HandleOnce 使用 handler 处理 path 的下一个请求, 多次调用时按照调用的顺序依次处理之后的请求
+func (s *Server) HandleOnce(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.once[path] = append(s.once[path], handler)
+}
+
+// InjectError 使 path 的下一个请求返回 errcode 和 errmsg
+func (s *Server) InjectError(path string, errCode int64, errMsg string) {
+	s.HandleOnce(path, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, errCode, errMsg)
+	})
+}
+
+// InjectStatus 使 path 的下一个请求返回 statusCode
+func (s *Server) InjectStatus(path string, statusCode int) {
+	s.HandleOnce(path, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(statusCode), statusCode)
+	})
+}
+
+// InjectNetworkError 使 path 的下一个请求在返回内容的过程中断开连接
+func (s *Server) InjectNetworkError(path string) {
+	s.HandleOnce(path, func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "hijack not supported", http.StatusInternalServerError)
+			return
+		}
+		conn, buf, err := hj.Hijack()
+		if err != nil {
+			return
+		}
+		//在连接断开之前返回部分内容, 避免 http.Transport 自动重试 GET 请求
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 100\r\n\r\n{")
+		buf.Flush()
+		conn.Close()
+	})
+}
+
+// Requests 返回收到的请求, path 不为空时只返回该路径的请求
+func (s *Server) Requests(path string) []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []*Request
+	for _, req := range s.requests {
+		if path == "" || req.Path == path {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// Reset 清除请求记录、脚本化的返回以及菜单、订单等数据, access_token 会在下次请求时重新生成
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessToken = ""
+	s.expired = false
+	s.menu = nil
+	s.users = nil
+	s.orders = make(map[string]*Order)
+	s.overrides = make(map[string]http.HandlerFunc)
+	s.once = make(map[string][]http.HandlerFunc)
+	s.requests = nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	s.requests = append(s.requests, &Request{
+		Method: r.Method,
+		Host:   r.Host,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	handler := s.overrides[r.URL.Path]
+	if queue := s.once[r.URL.Path]; len(queue) > 0 {
+		handler = queue[0]
+		s.once[r.URL.Path] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if handler == nil {
+		handler = s.handlers[r.URL.Path]
+	}
+	if handler == nil {
+		writeError(w, 40066, "invalid url")
+		return
+	}
+	handler(w, r)
+}
+
+//nextSeq 返回递增的序号, 用于生成 access_token、media_id 等
+func (s *Server) nextSeq() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return s.seq
+}
+
+//WriteJSON 返回 json 格式的内容
+func WriteJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; encoding=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, errCode int64, errMsg string) {
+	WriteJSON(w, map[string]interface{}{"errcode": errCode, "errmsg": errMsg})
+}
+
+func writeOK(w http.ResponseWriter) {
+	writeError(w, 0, "ok")
+}
+
+func invalidArgs(w http.ResponseWriter, format string, args ...interface{}) {
+	writeError(w, 47001, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,220 @@
+package wechattest_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/antsbean/wechat"
+	"github.com/antsbean/wechat/cache"
+	"github.com/antsbean/wechat/material"
+	"github.com/antsbean/wechat/menu"
+	"github.com/antsbean/wechat/message"
+	"github.com/antsbean/wechat/miniprogram"
+	"github.com/antsbean/wechat/pay"
+	"github.com/antsbean/wechat/util"
+	"github.com/antsbean/wechat/wechattest"
+)
+
+func newWechat(fake *wechattest.Server) *wechat.Wechat {
+	return wechat.NewWechat(&wechat.Config{
+		AppID:      fake.AppID,
+		AppSecret:  fake.AppSecret,
+		PayMchID:   fake.PayMchID,
+		PayKey:     fake.PayKey,
+		Cache:      cache.NewMemory(),
+		HTTPClient: fake.Client(),
+		Logger:     util.NopLogger{},
+	})
+}
+
+func TestOfficialAccount(t *testing.T) {
+	fake := wechattest.NewServer("appid", "secret")
+	defer fake.Close()
+	wc := newWechat(fake)
+
+	accessToken, err := wc.GetAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessToken != fake.AccessToken() {
+		t.Fatalf("access_token = %s, want %s", accessToken, fake.AccessToken())
+	}
+	if req := fake.Requests("/cgi-bin/token")[0]; req.Host != "api.weixin.qq.com" {
+		t.Errorf("host = %s, want api.weixin.qq.com", req.Host)
+	}
+
+	ticket, err := wc.GetJs().GetTicket()
+	if err != nil || !strings.HasPrefix(ticket, "TICKET_jsapi_") {
+		t.Errorf("GetTicket() = %s, %v", ticket, err)
+	}
+
+	buttons := []*menu.Button{new(menu.Button)}
+	buttons[0].SetClickButton("今日歌曲", "V1001_TODAY_MUSIC")
+	if err := wc.GetMenu().SetMenu(buttons); err != nil {
+		t.Fatal(err)
+	}
+	resMenu, err := wc.GetMenu().GetMenu()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resMenu.Menu.Button) != 1 || resMenu.Menu.Button[0].Key != "V1001_TODAY_MUSIC" {
+		t.Errorf("GetMenu() = %+v", resMenu.Menu)
+	}
+
+	fake.SetUsers("openid1", "openid2")
+	info, err := wc.GetUser().GetUserInfo("openid1")
+	if err != nil || info.OpenID != "openid1" {
+		t.Errorf("GetUserInfo() = %+v, %v", info, err)
+	}
+	if _, err := wc.GetUser().GetUserInfo("unknown"); !util.IsInvalidOpenID(err) {
+		t.Errorf("GetUserInfo(unknown) error = %v, want 40003", err)
+	}
+	openIDs, err := wc.GetUser().ListAllUserOpenIDs()
+	if err != nil || len(openIDs) != 2 {
+		t.Errorf("ListAllUserOpenIDs() = %v, %v", openIDs, err)
+	}
+
+	msgID, err := wc.GetTemplate().Send(&message.Message{
+		ToUser:     "openid2",
+		TemplateID: "template_id",
+		Data:       map[string]*message.DataItem{"first": {Value: "hello"}},
+	})
+	if err != nil || msgID == 0 {
+		t.Errorf("Send() = %d, %v", msgID, err)
+	}
+
+	file, err := ioutil.TempFile("", "media*.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("image")
+	file.Close()
+	media, err := wc.GetMaterial().MediaUpload(material.MediaTypeImage, file.Name())
+	if err != nil || media.MediaID == "" {
+		t.Errorf("MediaUpload() = %+v, %v", media, err)
+	}
+}
+
+func TestMiniProgram(t *testing.T) {
+	fake := wechattest.NewServer("appid", "secret")
+	defer fake.Close()
+	wxa := newWechat(fake).GetMiniProgram()
+
+	session, err := wxa.Code2Session("code")
+	if err != nil || session.OpenID != "OPENID_code" {
+		t.Errorf("Code2Session() = %+v, %v", session, err)
+	}
+
+	code, err := wxa.GetWXACode(miniprogram.QRCoder{Path: "pages/index"})
+	if err != nil || !bytes.Equal(code, wechattest.WXACodeImage) {
+		t.Errorf("GetWXACode() = %q, %v", code, err)
+	}
+	if _, err := wxa.GetWXACodeUnlimit(miniprogram.QRCoder{}); err == nil {
+		t.Error("GetWXACodeUnlimit() without scene should fail")
+	}
+}
+
+func TestPay(t *testing.T) {
+	fake := wechattest.NewServer("appid", "secret")
+	fake.PayMchID = "mchid"
+	fake.PayKey = "paykey"
+	defer fake.Close()
+	p := newWechat(fake).GetPay()
+
+	prepayID, err := p.PrePayID(&pay.Params{
+		TotalFee:   "100",
+		CreateIP:   "127.0.0.1",
+		Body:       "body",
+		OutTradeNo: "order1",
+		OpenID:     "openid",
+		TradeType:  "JSAPI",
+		NotifyURL:  "https://example.com/notify",
+	})
+	if err != nil || prepayID == "" {
+		t.Fatalf("PrePayID() = %s, %v", prepayID, err)
+	}
+
+	rsp, err := p.QueryOrder(&pay.QueryOrderParams{OutTradeNo: "order1"})
+	if err != nil || rsp.TradeState != pay.TradeNOTPAY {
+		t.Fatalf("QueryOrder() = %+v, %v", rsp, err)
+	}
+	fake.SetTradeState("order1", pay.TradeSuccess)
+	rsp, err = p.QueryOrder(&pay.QueryOrderParams{OutTradeNo: "order1"})
+	if err != nil || rsp.TradeState != pay.TradeSuccess || rsp.TransactionID == "" {
+		t.Fatalf("QueryOrder() = %+v, %v", rsp, err)
+	}
+	if _, err := p.QueryOrder(&pay.QueryOrderParams{OutTradeNo: "order2"}); !pay.IsOrderNotExist(err) {
+		t.Errorf("QueryOrder(order2) error = %v, want ORDERNOTEXIST", err)
+	}
+
+	//模拟服务不校验客户端证书, 这里只需要一个可以读取的文件
+	cert, err := ioutil.TempFile("", "apiclient_cert*.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(cert.Name())
+	cert.Close()
+	refund, err := p.Refund(&pay.RefundParams{
+		TransactionID: rsp.TransactionID,
+		OutRefundNo:   "refund1",
+		TotalFee:      "100",
+		RefundFee:     "100",
+		RootCa:        cert.Name(),
+	})
+	if err != nil || refund.RefundID == "" {
+		t.Fatalf("Refund() = %+v, %v", refund, err)
+	}
+	if order, _ := fake.Order("order1"); order.TradeState != pay.TradeRefund {
+		t.Errorf("trade state = %s, want %s", order.TradeState, pay.TradeRefund)
+	}
+
+	fake.InjectPayError("/pay/orderquery", pay.ErrCodeSystemError, "系统错误")
+	if _, err := p.QueryOrder(&pay.QueryOrderParams{OutTradeNo: "order1"}); !pay.IsSystemError(err) {
+		t.Errorf("QueryOrder() error = %v, want SYSTEMERROR", err)
+	}
+
+	fake.PayKey = "wrong"
+	_, err = p.QueryOrder(&pay.QueryOrderParams{OutTradeNo: "order1"})
+	var apiErr *pay.APIError
+	if !errors.As(err, &apiErr) || apiErr.ReturnCode != "FAIL" {
+		t.Errorf("QueryOrder() with wrong sign error = %v, want return_code FAIL", err)
+	}
+}
+
+func TestInject(t *testing.T) {
+	fake := wechattest.NewServer("appid", "secret")
+	defer fake.Close()
+	wc := newWechat(fake)
+
+	fake.InjectError("/cgi-bin/user/info", 45009, "reach max api daily quota limit")
+	if _, err := wc.GetUser().GetUserInfo("openid"); !util.IsQuotaExceeded(err) {
+		t.Errorf("GetUserInfo() error = %v, want 45009", err)
+	}
+	if _, err := wc.GetUser().GetUserInfo("openid"); err != nil {
+		t.Errorf("GetUserInfo() error = %v after injected error", err)
+	}
+
+	fake.InjectStatus("/cgi-bin/user/info", 502)
+	if _, err := wc.GetUser().GetUserInfo("openid"); err == nil {
+		t.Error("GetUserInfo() should fail with status 502")
+	}
+
+	fake.InjectNetworkError("/cgi-bin/user/info")
+	if _, err := wc.GetUser().GetUserInfo("openid"); err == nil {
+		t.Error("GetUserInfo() should fail with network error")
+	}
+
+	//access_token 被提前作废时 SDK 会重新获取并重试
+	fake.ExpireAccessToken()
+	if _, err := wc.GetUser().GetUserInfo("openid"); err != nil {
+		t.Errorf("GetUserInfo() error = %v after access_token expired", err)
+	}
+	if n := len(fake.Requests("/cgi-bin/token")); n != 2 {
+		t.Errorf("token requests = %d, want 2", n)
+	}
+}